@@ -0,0 +1,114 @@
+// Command localdriver serves driverlocal's LocalDriver, a volume driver that
+// backs volumes with directories. It is for development and for running the
+// integration suite without a storage server.
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/dockerdriver/driverlocal"
+	"code.cloudfoundry.org/dockerdriver/driverreconcile"
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/tedsuo/ifrit"
+)
+
+var (
+	listenAddr  = flag.String("listenAddr", "127.0.0.1:9750", "host:port, or socket path for the unix transport, to serve on")
+	transport   = flag.String("transport", driverhttp.TransportTCP, "tcp or unix")
+	driversPath = flag.String("driversPath", "", "directory to write the driver's spec to; none is written if empty")
+	rootDir     = flag.String("rootDir", "", "directory to keep volumes and state in; defaults to a new temporary directory, removed on exit")
+	mountDir    = flag.String("mountDir", "", "directory to make mountpoints in; defaults to <rootDir>/mounts")
+	bindMounts  = flag.Bool("bindMounts", false, "bind mount volumes rather than symlinking them; needs root")
+)
+
+func main() {
+	flag.Parse()
+
+	logger := lager.NewLogger("localdriver")
+	logger.RegisterSink(lager.NewWriterSink(os.Stdout, lager.INFO))
+
+	if err := run(logger); err != nil {
+		os.Exit(1)
+	}
+}
+
+// run logs its own failures; it returns rather than exits so that a temporary
+// root is always removed.
+func run(logger lager.Logger) error {
+	// a fresh root by default, so one run's volumes can't trip up the next
+	root := *rootDir
+	if root == "" {
+		var err error
+		root, err = os.MkdirTemp("", "localdriver-")
+		if err != nil {
+			logger.Error("failed-creating-root-dir", err)
+			return err
+		}
+		defer removeRoot(logger, root)
+	}
+
+	driver, err := driverlocal.NewLocalDriver(logger, driverlocal.Config{
+		RootDir:    root,
+		MountDir:   *mountDir,
+		BindMounts: *bindMounts,
+	})
+	if err != nil {
+		logger.Error("failed-creating-driver", err)
+		return err
+	}
+
+	// symlinks aren't in the mount table, so only bind mounts can be
+	// reconciled against it
+	if *bindMounts {
+		reconciler := driver.Reconciler(logger, clock.NewClock(), driverreconcile.DefaultPolicy())
+		if _, err := reconciler.Reconcile(context.Background()); err != nil {
+			logger.Error("failed-reconciling", err)
+			return err
+		}
+	}
+
+	server, err := driverhttp.NewDriverServer(logger, driverhttp.DriverServerConfig{
+		DriverName:    "localdriver",
+		Transport:     *transport,
+		ListenAddress: *listenAddr,
+		DriversPath:   *driversPath,
+	}, driver)
+	if err != nil {
+		logger.Error("failed-creating-server", err)
+		return err
+	}
+
+	if err := serve(server); err != nil {
+		logger.Error("exited-with-failure", err)
+		return err
+	}
+	return nil
+}
+
+func serve(server ifrit.Runner) error {
+	process := ifrit.Invoke(server)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	for {
+		select {
+		case sig := <-signals:
+			process.Signal(sig)
+		case err := <-process.Wait():
+			return err
+		}
+	}
+}
+
+func removeRoot(logger lager.Logger, root string) {
+	if err := os.RemoveAll(root); err != nil {
+		logger.Error("failed-removing-root-dir", err, lager.Data{"root-dir": root})
+	}
+}
@@ -0,0 +1,13 @@
+package dockerdriver_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestDockerdriver(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Dockerdriver Suite")
+}
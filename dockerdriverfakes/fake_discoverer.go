@@ -0,0 +1,183 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package dockerdriverfakes
+
+import (
+	"sync"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverdiscovery"
+	lager "code.cloudfoundry.org/lager/v3"
+)
+
+type FakeDiscoverer struct {
+	DirectoriesStub        func() []string
+	directoriesMutex       sync.RWMutex
+	directoriesArgsForCall []struct {
+	}
+	directoriesReturns struct {
+		result1 []string
+	}
+	directoriesReturnsOnCall map[int]struct {
+		result1 []string
+	}
+	DiscoverStub        func(lager.Logger) ([]dockerdriver.DriverSpec, error)
+	discoverMutex       sync.RWMutex
+	discoverArgsForCall []struct {
+		arg1 lager.Logger
+	}
+	discoverReturns struct {
+		result1 []dockerdriver.DriverSpec
+		result2 error
+	}
+	discoverReturnsOnCall map[int]struct {
+		result1 []dockerdriver.DriverSpec
+		result2 error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeDiscoverer) Directories() []string {
+	fake.directoriesMutex.Lock()
+	ret, specificReturn := fake.directoriesReturnsOnCall[len(fake.directoriesArgsForCall)]
+	fake.directoriesArgsForCall = append(fake.directoriesArgsForCall, struct {
+	}{})
+	stub := fake.DirectoriesStub
+	fakeReturns := fake.directoriesReturns
+	fake.recordInvocation("Directories", []interface{}{})
+	fake.directoriesMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeDiscoverer) DirectoriesCallCount() int {
+	fake.directoriesMutex.RLock()
+	defer fake.directoriesMutex.RUnlock()
+	return len(fake.directoriesArgsForCall)
+}
+
+func (fake *FakeDiscoverer) DirectoriesCalls(stub func() []string) {
+	fake.directoriesMutex.Lock()
+	defer fake.directoriesMutex.Unlock()
+	fake.DirectoriesStub = stub
+}
+
+func (fake *FakeDiscoverer) DirectoriesReturns(result1 []string) {
+	fake.directoriesMutex.Lock()
+	defer fake.directoriesMutex.Unlock()
+	fake.DirectoriesStub = nil
+	fake.directoriesReturns = struct {
+		result1 []string
+	}{result1}
+}
+
+func (fake *FakeDiscoverer) DirectoriesReturnsOnCall(i int, result1 []string) {
+	fake.directoriesMutex.Lock()
+	defer fake.directoriesMutex.Unlock()
+	fake.DirectoriesStub = nil
+	if fake.directoriesReturnsOnCall == nil {
+		fake.directoriesReturnsOnCall = make(map[int]struct {
+			result1 []string
+		})
+	}
+	fake.directoriesReturnsOnCall[i] = struct {
+		result1 []string
+	}{result1}
+}
+
+func (fake *FakeDiscoverer) Discover(arg1 lager.Logger) ([]dockerdriver.DriverSpec, error) {
+	fake.discoverMutex.Lock()
+	ret, specificReturn := fake.discoverReturnsOnCall[len(fake.discoverArgsForCall)]
+	fake.discoverArgsForCall = append(fake.discoverArgsForCall, struct {
+		arg1 lager.Logger
+	}{arg1})
+	stub := fake.DiscoverStub
+	fakeReturns := fake.discoverReturns
+	fake.recordInvocation("Discover", []interface{}{arg1})
+	fake.discoverMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeDiscoverer) DiscoverCallCount() int {
+	fake.discoverMutex.RLock()
+	defer fake.discoverMutex.RUnlock()
+	return len(fake.discoverArgsForCall)
+}
+
+func (fake *FakeDiscoverer) DiscoverCalls(stub func(lager.Logger) ([]dockerdriver.DriverSpec, error)) {
+	fake.discoverMutex.Lock()
+	defer fake.discoverMutex.Unlock()
+	fake.DiscoverStub = stub
+}
+
+func (fake *FakeDiscoverer) DiscoverArgsForCall(i int) lager.Logger {
+	fake.discoverMutex.RLock()
+	defer fake.discoverMutex.RUnlock()
+	argsForCall := fake.discoverArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeDiscoverer) DiscoverReturns(result1 []dockerdriver.DriverSpec, result2 error) {
+	fake.discoverMutex.Lock()
+	defer fake.discoverMutex.Unlock()
+	fake.DiscoverStub = nil
+	fake.discoverReturns = struct {
+		result1 []dockerdriver.DriverSpec
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeDiscoverer) DiscoverReturnsOnCall(i int, result1 []dockerdriver.DriverSpec, result2 error) {
+	fake.discoverMutex.Lock()
+	defer fake.discoverMutex.Unlock()
+	fake.DiscoverStub = nil
+	if fake.discoverReturnsOnCall == nil {
+		fake.discoverReturnsOnCall = make(map[int]struct {
+			result1 []dockerdriver.DriverSpec
+			result2 error
+		})
+	}
+	fake.discoverReturnsOnCall[i] = struct {
+		result1 []dockerdriver.DriverSpec
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeDiscoverer) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.directoriesMutex.RLock()
+	defer fake.directoriesMutex.RUnlock()
+	fake.discoverMutex.RLock()
+	defer fake.discoverMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeDiscoverer) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ driverdiscovery.Discoverer = new(FakeDiscoverer)
@@ -0,0 +1,76 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package dockerdriverfakes
+
+import (
+	"sync"
+
+	"code.cloudfoundry.org/dockerdriver/drivertracing"
+)
+
+type FakeExporter struct {
+	ExportSpanStub        func(drivertracing.SpanData)
+	exportSpanMutex       sync.RWMutex
+	exportSpanArgsForCall []struct {
+		arg1 drivertracing.SpanData
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeExporter) ExportSpan(arg1 drivertracing.SpanData) {
+	fake.exportSpanMutex.Lock()
+	fake.exportSpanArgsForCall = append(fake.exportSpanArgsForCall, struct {
+		arg1 drivertracing.SpanData
+	}{arg1})
+	stub := fake.ExportSpanStub
+	fake.recordInvocation("ExportSpan", []interface{}{arg1})
+	fake.exportSpanMutex.Unlock()
+	if stub != nil {
+		fake.ExportSpanStub(arg1)
+	}
+}
+
+func (fake *FakeExporter) ExportSpanCallCount() int {
+	fake.exportSpanMutex.RLock()
+	defer fake.exportSpanMutex.RUnlock()
+	return len(fake.exportSpanArgsForCall)
+}
+
+func (fake *FakeExporter) ExportSpanCalls(stub func(drivertracing.SpanData)) {
+	fake.exportSpanMutex.Lock()
+	defer fake.exportSpanMutex.Unlock()
+	fake.ExportSpanStub = stub
+}
+
+func (fake *FakeExporter) ExportSpanArgsForCall(i int) drivertracing.SpanData {
+	fake.exportSpanMutex.RLock()
+	defer fake.exportSpanMutex.RUnlock()
+	argsForCall := fake.exportSpanArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeExporter) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.exportSpanMutex.RLock()
+	defer fake.exportSpanMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeExporter) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ drivertracing.Exporter = new(FakeExporter)
@@ -4,39 +4,122 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net"
+	"net/url"
 	"os"
 	"path"
+	"path/filepath"
 	"strings"
 
 	"code.cloudfoundry.org/lager/v3"
 )
 
+const (
+	DriverSpecDirMode  os.FileMode = 0755
+	DriverSpecFileMode os.FileMode = 0644
+)
+
+// WriteDriverSpec replaces the spec atomically: the contents are written and
+// synced to a temporary file in the plugins directory which is then renamed
+// over the spec, so a concurrent ReadDriverSpec sees either the old spec or
+// the new one, never a partial write.
 func WriteDriverSpec(logger lager.Logger, pluginsDirectory string, driver string, extension string, contents []byte) error {
-	err := os.MkdirAll(pluginsDirectory, 0755)
+	err := os.MkdirAll(pluginsDirectory, DriverSpecDirMode)
 	if err != nil {
 		logger.Error("error-creating-directory", err)
 		return err
 	}
 
-	f, err := os.Create(path.Join(pluginsDirectory, driver+"."+extension))
+	specPath := path.Join(pluginsDirectory, driver+"."+extension)
+
+	// the leading dot and .tmp-* extension keep the temporary file out of discovery
+	f, err := os.CreateTemp(pluginsDirectory, "."+driver+"."+extension+".tmp-*")
 	if err != nil {
 		logger.Error("error-creating-file ", err)
 		return err
 	}
+	tempPath := f.Name()
+	defer os.Remove(tempPath) // a no-op once the rename has happened
 	defer f.Close()
+
 	_, err = f.Write(contents)
 	if err != nil {
 		logger.Error("error-writing-file ", err)
 		return err
 	}
+	err = f.Chmod(DriverSpecFileMode)
+	if err != nil {
+		logger.Error("error-setting-file-mode ", err)
+		return err
+	}
 	err = f.Sync()
 	if err != nil {
 		logger.Error("error-syncing-file ", err)
 		return err
 	}
+	err = f.Close()
+	if err != nil {
+		logger.Error("error-closing-file ", err)
+		return err
+	}
+
+	err = os.Rename(tempPath, specPath)
+	if err != nil {
+		logger.Error("error-renaming-file ", err)
+		return err
+	}
+
+	err = syncDirectory(pluginsDirectory)
+	if err != nil {
+		logger.Error("error-syncing-directory ", err)
+		return err
+	}
 	return nil
 }
 
+// WriteDriverSpecFile generates the contents for the given extension with
+// GenerateDriverSpec and writes them as <spec.Name>.<extension>.
+func WriteDriverSpecFile(logger lager.Logger, pluginsDirectory string, spec DriverSpec, extension string) error {
+	contents, err := GenerateDriverSpec(spec, extension)
+	if err != nil {
+		logger.Error("error-generating-spec", err)
+		return err
+	}
+	return WriteDriverSpec(logger, pluginsDirectory, spec.Name, extension, contents)
+}
+
+// GenerateDriverSpec serializes spec in the format docker expects for the
+// extension: a bare address for "spec" and a json document for "json". A
+// .spec file cannot carry TLS settings or UniqueVolumeIds, so asking for one
+// when they are set is an error.
+func GenerateDriverSpec(spec DriverSpec, extension string) ([]byte, error) {
+	if err := validateAddress(spec.Address); err != nil {
+		return nil, err
+	}
+
+	switch extension {
+	case "spec":
+		if spec.TLSConfig != nil || spec.UniqueVolumeIds {
+			return nil, fmt.Errorf("driver spec %q has settings that need a json spec", spec.Name)
+		}
+		return []byte(spec.Address), nil
+	case "json":
+		return json.Marshal(spec)
+	default:
+		return nil, fmt.Errorf("unknown-driver-extension: %s", extension)
+	}
+}
+
+func syncDirectory(directory string) error {
+	d, err := os.Open(directory)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
 func ReadDriverSpec(logger lager.Logger, specName string, driverPath string, specFile string) (*DriverSpec, error) {
 	logger = logger.Session("read-driver-spec", lager.Data{"spec-name": specName, "spec-file": specFile})
 	logger.Info("start")
@@ -44,55 +127,154 @@ func ReadDriverSpec(logger lager.Logger, specName string, driverPath string, spe
 
 	var driverSpec DriverSpec
 
-	if strings.Contains(specFile, ".") {
-		extension := strings.Split(specFile, ".")[1]
-		switch extension {
-		case "sock":
-			driverSpec = DriverSpec{
-				Name:    specName,
-				Address: path.Join(driverPath, specFile),
-			}
-		case "spec":
-			configFile, err := os.Open(path.Join(driverPath, specFile))
-			if err != nil {
-				logger.Error("error-opening-config", err, lager.Data{"DriverFileName": specFile})
-				return nil, err
-			}
-			reader := bufio.NewReader(configFile)
-			addressBytes, _, err := reader.ReadLine()
-			if err != nil { // no real value in faking this as bigger problems exist when this fails
-				logger.Error("error-reading-driver-file", err, lager.Data{"DriverFileName": specFile})
-				return nil, err
-			}
-			driverSpec = DriverSpec{
-				Name:    specName,
-				Address: string(addressBytes),
-			}
-		case "json":
-			// extract url from json file
-			var driverJsonSpec DriverSpec
-			configFile, err := os.Open(path.Join(driverPath, specFile))
-			if err != nil {
-				logger.Error("error-opening-config", err, lager.Data{"DriverFileName": specFile})
-				return nil, err
-			}
-			jsonParser := json.NewDecoder(configFile)
-			if err = jsonParser.Decode(&driverJsonSpec); err != nil {
-				logger.Error("parsing-config-file-error", err)
-				return nil, err
-			}
-			driverSpec = DriverSpec{
-				Name:            specName,
-				Address:         driverJsonSpec.Address,
-				TLSConfig:       driverJsonSpec.TLSConfig,
-				UniqueVolumeIds: driverJsonSpec.UniqueVolumeIds,
-			}
-		default:
-			err := fmt.Errorf("unknown-driver-extension: %s", extension)
-			logger.Error("driver", err)
-			return nil, err
+	extension := strings.TrimPrefix(filepath.Ext(specFile), ".")
+	switch extension {
+	case "sock":
+		driverSpec = DriverSpec{
+			Name:    specName,
+			Address: path.Join(driverPath, specFile),
+		}
+	case "spec":
+		address, err := readSpecAddress(path.Join(driverPath, specFile))
+		if err != nil {
+			logger.Error("error-reading-driver-file", err, lager.Data{"DriverFileName": specFile})
+			return nil, &DriverSpecError{SpecFile: specFile, Err: err}
+		}
+		driverSpec = DriverSpec{
+			Name:    specName,
+			Address: address,
 		}
+	case "json":
+		// extract url from json file
+		var driverJsonSpec DriverSpec
+		if err := readJSONSpec(path.Join(driverPath, specFile), &driverJsonSpec); err != nil {
+			logger.Error("parsing-config-file-error", err, lager.Data{"DriverFileName": specFile})
+			return nil, &DriverSpecError{SpecFile: specFile, Err: err}
+		}
+		driverSpec = DriverSpec{
+			Name:            specName,
+			Address:         driverJsonSpec.Address,
+			TLSConfig:       driverJsonSpec.TLSConfig,
+			UniqueVolumeIds: driverJsonSpec.UniqueVolumeIds,
+		}
+	default:
+		err := &DriverSpecError{SpecFile: specFile, Err: fmt.Errorf("%w: %q", ErrUnknownSpecExtension, extension)}
+		logger.Error("driver", err)
+		return nil, err
 	}
 
+	if err := ValidateDriverSpec(&driverSpec); err != nil {
+		logger.Error("invalid-driver-spec", err)
+		return nil, &DriverSpecError{SpecFile: specFile, Err: err}
+	}
+	warnOnInsecureSpec(logger, &driverSpec)
+
 	return &driverSpec, nil
 }
+
+// ValidateDriverSpec checks that the address is one a client can dial and
+// that any TLS files the spec refers to can be read.
+func ValidateDriverSpec(spec *DriverSpec) error {
+	if err := validateAddress(spec.Address); err != nil {
+		return err
+	}
+
+	if spec.TLSConfig == nil {
+		return nil
+	}
+	if (spec.TLSConfig.CertFile == "") != (spec.TLSConfig.KeyFile == "") {
+		return fmt.Errorf("%w: CertFile and KeyFile must be set together", ErrInvalidTLSConfig)
+	}
+	for _, file := range []string{spec.TLSConfig.CAFile, spec.TLSConfig.CertFile, spec.TLSConfig.KeyFile} {
+		if file == "" {
+			continue
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidTLSConfig, err)
+		}
+		f.Close()
+	}
+	return nil
+}
+
+func readSpecAddress(specPath string) (string, error) {
+	configFile, err := os.Open(specPath)
+	if err != nil {
+		return "", err
+	}
+	defer configFile.Close()
+
+	reader := bufio.NewReader(configFile)
+	addressBytes, _, err := reader.ReadLine()
+	if err == io.EOF {
+		return "", ErrEmptyAddress
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(addressBytes)), nil
+}
+
+func readJSONSpec(specPath string, spec *DriverSpec) error {
+	configFile, err := os.Open(specPath)
+	if err != nil {
+		return err
+	}
+	defer configFile.Close()
+
+	return json.NewDecoder(configFile).Decode(spec)
+}
+
+// validateAddress accepts the address forms docker does: a socket path,
+// unix://, tcp://, http(s):// or a bare host:port.
+func validateAddress(address string) error {
+	if address == "" {
+		return ErrEmptyAddress
+	}
+
+	if strings.HasPrefix(address, "/") || filepath.Ext(address) == ".sock" {
+		return nil
+	}
+
+	if !strings.Contains(address, "://") {
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			return fmt.Errorf("%w: %q", ErrInvalidAddress, address)
+		}
+		return nil
+	}
+
+	u, err := url.Parse(address)
+	if err != nil {
+		return fmt.Errorf("%w: %q", ErrInvalidAddress, address)
+	}
+	switch u.Scheme {
+	case "unix":
+		if u.Host == "" && u.Path == "" {
+			return fmt.Errorf("%w: %q", ErrInvalidAddress, address)
+		}
+	case "tcp", "http", "https":
+		if u.Host == "" {
+			return fmt.Errorf("%w: %q", ErrInvalidAddress, address)
+		}
+	default:
+		return fmt.Errorf("%w: unsupported scheme in %q", ErrInvalidAddress, address)
+	}
+	return nil
+}
+
+func warnOnInsecureSpec(logger lager.Logger, spec *DriverSpec) {
+	if spec.TLSConfig == nil || isUnixAddress(spec.Address) {
+		return
+	}
+	if strings.HasPrefix(spec.Address, "http://") {
+		logger.Info("warning-tls-config-ignored-for-http-address", lager.Data{"address": spec.Address})
+	}
+	if spec.TLSConfig.InsecureSkipVerify {
+		logger.Info("warning-insecure-skip-verify", lager.Data{"address": spec.Address})
+	}
+}
+
+func isUnixAddress(address string) bool {
+	return strings.HasPrefix(address, "/") || strings.HasPrefix(address, "unix://") || filepath.Ext(address) == ".sock"
+}
@@ -0,0 +1,28 @@
+package dockerdriver
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrUnknownSpecExtension = errors.New("unknown-driver-extension")
+	ErrEmptyAddress         = errors.New("driver address is empty")
+	ErrInvalidAddress       = errors.New("driver address is invalid")
+	ErrInvalidTLSConfig     = errors.New("driver tls config is invalid")
+)
+
+// DriverSpecError is returned by ReadDriverSpec. Use errors.Is with the Err*
+// values above to find out what was wrong with the spec.
+type DriverSpecError struct {
+	SpecFile string
+	Err      error
+}
+
+func (e *DriverSpecError) Error() string {
+	return fmt.Sprintf("invalid driver spec %s: %s", e.SpecFile, e.Err)
+}
+
+func (e *DriverSpecError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,262 @@
+package dockerdriver_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DriverSpec", func() {
+	var (
+		testLogger  *lagertest.TestLogger
+		pluginsPath string
+	)
+
+	BeforeEach(func() {
+		testLogger = lagertest.NewTestLogger("driver-spec-test")
+		pluginsPath = filepath.Join(GinkgoT().TempDir(), "plugins")
+	})
+
+	Describe("WriteDriverSpec", func() {
+		It("should create the plugins directory and write the spec", func() {
+			err := dockerdriver.WriteDriverSpec(testLogger, pluginsPath, "some-driver", "spec", []byte("http://127.0.0.1:7589"))
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := os.ReadFile(filepath.Join(pluginsPath, "some-driver.spec"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal("http://127.0.0.1:7589"))
+		})
+
+		It("should write the spec with the spec file mode", func() {
+			Expect(dockerdriver.WriteDriverSpec(testLogger, pluginsPath, "some-driver", "spec", []byte("http://127.0.0.1:7589"))).To(Succeed())
+
+			info, err := os.Stat(filepath.Join(pluginsPath, "some-driver.spec"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Mode().Perm()).To(Equal(dockerdriver.DriverSpecFileMode))
+		})
+
+		It("should replace an existing spec without leaving temporary files behind", func() {
+			Expect(dockerdriver.WriteDriverSpec(testLogger, pluginsPath, "some-driver", "spec", []byte("http://a-much-longer-old-address:7589"))).To(Succeed())
+			Expect(dockerdriver.WriteDriverSpec(testLogger, pluginsPath, "some-driver", "spec", []byte("http://new:7589"))).To(Succeed())
+
+			contents, err := os.ReadFile(filepath.Join(pluginsPath, "some-driver.spec"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal("http://new:7589"))
+
+			entries, err := os.ReadDir(pluginsPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+		})
+	})
+
+	Describe("GenerateDriverSpec", func() {
+		var spec dockerdriver.DriverSpec
+
+		BeforeEach(func() {
+			spec = dockerdriver.DriverSpec{Name: "some-driver", Address: "http://127.0.0.1:7589"}
+		})
+
+		It("should generate a bare address for a .spec", func() {
+			contents, err := dockerdriver.GenerateDriverSpec(spec, "spec")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal("http://127.0.0.1:7589"))
+		})
+
+		It("should generate json including the tls config and unique volume ids", func() {
+			spec.TLSConfig = &dockerdriver.TLSConfig{CAFile: "/ca.crt", CertFile: "/client.crt", KeyFile: "/client.key"}
+			spec.UniqueVolumeIds = true
+
+			contents, err := dockerdriver.GenerateDriverSpec(spec, "json")
+			Expect(err).NotTo(HaveOccurred())
+
+			var decoded dockerdriver.DriverSpec
+			Expect(json.Unmarshal(contents, &decoded)).To(Succeed())
+			Expect(decoded).To(Equal(spec))
+			Expect(string(contents)).To(ContainSubstring(`"Addr":"http://127.0.0.1:7589"`))
+		})
+
+		It("should refuse to put tls settings in a .spec", func() {
+			spec.TLSConfig = &dockerdriver.TLSConfig{CAFile: "/ca.crt"}
+
+			_, err := dockerdriver.GenerateDriverSpec(spec, "spec")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should refuse to generate a socket", func() {
+			_, err := dockerdriver.GenerateDriverSpec(spec, "sock")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should refuse a spec with no address", func() {
+			spec.Address = ""
+
+			_, err := dockerdriver.GenerateDriverSpec(spec, "json")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("ReadDriverSpec", func() {
+		var (
+			specFile string
+			spec     *dockerdriver.DriverSpec
+			err      error
+		)
+
+		BeforeEach(func() {
+			Expect(os.MkdirAll(pluginsPath, 0755)).To(Succeed())
+		})
+
+		JustBeforeEach(func() {
+			spec, err = dockerdriver.ReadDriverSpec(testLogger, "some-driver", pluginsPath, specFile)
+		})
+
+		Context("when the file name contains dots", func() {
+			BeforeEach(func() {
+				specFile = "my.driver.json"
+				writeSpec(pluginsPath, specFile, `{"Addr":"http://127.0.0.1:7589"}`)
+			})
+
+			It("should use the last extension", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(spec.Address).To(Equal("http://127.0.0.1:7589"))
+			})
+		})
+
+		Context("when the file has no extension", func() {
+			BeforeEach(func() {
+				specFile = "some-driver"
+				writeSpec(pluginsPath, specFile, "http://127.0.0.1:7589")
+			})
+
+			It("should return an unknown extension error", func() {
+				Expect(err).To(MatchError(dockerdriver.ErrUnknownSpecExtension))
+				Expect(spec).To(BeNil())
+
+				var specErr *dockerdriver.DriverSpecError
+				Expect(errors.As(err, &specErr)).To(BeTrue())
+				Expect(specErr.SpecFile).To(Equal("some-driver"))
+			})
+		})
+
+		Context("when the .spec file is empty", func() {
+			BeforeEach(func() {
+				specFile = "some-driver.spec"
+				writeSpec(pluginsPath, specFile, "")
+			})
+
+			It("should return an empty address error", func() {
+				Expect(err).To(MatchError(dockerdriver.ErrEmptyAddress))
+			})
+		})
+
+		Context("when the .spec file has surrounding whitespace", func() {
+			BeforeEach(func() {
+				specFile = "some-driver.spec"
+				writeSpec(pluginsPath, specFile, "  tcp://127.0.0.1:7589  \n")
+			})
+
+			It("should trim it", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(spec.Address).To(Equal("tcp://127.0.0.1:7589"))
+			})
+		})
+
+		DescribeTable("malformed addresses",
+			func(address string) {
+				writeSpec(pluginsPath, "bad-driver.spec", address)
+
+				_, err := dockerdriver.ReadDriverSpec(testLogger, "bad-driver", pluginsPath, "bad-driver.spec")
+				Expect(err).To(MatchError(dockerdriver.ErrInvalidAddress))
+			},
+			Entry("no port", "some-host"),
+			Entry("no tcp host", "tcp://"),
+			Entry("no unix path", "unix://"),
+			Entry("unsupported scheme", "ftp://some-host:21"),
+		)
+
+		Context("when the json is malformed", func() {
+			BeforeEach(func() {
+				specFile = "some-driver.json"
+				writeSpec(pluginsPath, specFile, `{"Addr":`)
+			})
+
+			It("should return a driver spec error", func() {
+				var specErr *dockerdriver.DriverSpecError
+				Expect(errors.As(err, &specErr)).To(BeTrue())
+			})
+		})
+
+		Context("when the json refers to tls files", func() {
+			var caFile string
+
+			BeforeEach(func() {
+				caFile = filepath.Join(GinkgoT().TempDir(), "ca.crt")
+				Expect(os.WriteFile(caFile, []byte("some-ca"), 0644)).To(Succeed())
+				specFile = "some-driver.json"
+			})
+
+			Context("when they exist", func() {
+				BeforeEach(func() {
+					writeSpec(pluginsPath, specFile, `{"Addr":"https://127.0.0.1:7589","TLSConfig":{"CAFile":"`+caFile+`"}}`)
+				})
+
+				It("should read the spec", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(spec.TLSConfig.CAFile).To(Equal(caFile))
+				})
+			})
+
+			Context("when one is missing", func() {
+				BeforeEach(func() {
+					writeSpec(pluginsPath, specFile, `{"Addr":"https://127.0.0.1:7589","TLSConfig":{"CAFile":"`+caFile+`","CertFile":"/does/not/exist.crt","KeyFile":"/does/not/exist.key"}}`)
+				})
+
+				It("should return a tls config error", func() {
+					Expect(err).To(MatchError(dockerdriver.ErrInvalidTLSConfig))
+				})
+			})
+
+			Context("when a cert has no key", func() {
+				BeforeEach(func() {
+					writeSpec(pluginsPath, specFile, `{"Addr":"https://127.0.0.1:7589","TLSConfig":{"CertFile":"`+caFile+`"}}`)
+				})
+
+				It("should return a tls config error", func() {
+					Expect(err).To(MatchError(dockerdriver.ErrInvalidTLSConfig))
+				})
+			})
+
+			Context("when verification is skipped over tcp", func() {
+				BeforeEach(func() {
+					writeSpec(pluginsPath, specFile, `{"Addr":"https://127.0.0.1:7589","TLSConfig":{"InsecureSkipVerify":true,"CAFile":"`+caFile+`"}}`)
+				})
+
+				It("should warn", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(testLogger.LogMessages()).To(ContainElement(ContainSubstring("warning-insecure-skip-verify")))
+				})
+			})
+		})
+	})
+
+	Describe("WriteDriverSpecFile", func() {
+		It("should write a spec that ReadDriverSpec reads back", func() {
+			spec := dockerdriver.DriverSpec{Name: "some-driver", Address: "https://127.0.0.1:7589", UniqueVolumeIds: true}
+			Expect(dockerdriver.WriteDriverSpecFile(testLogger, pluginsPath, spec, "json")).To(Succeed())
+
+			readSpec, err := dockerdriver.ReadDriverSpec(testLogger, "some-driver", pluginsPath, "some-driver.json")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*readSpec).To(Equal(spec))
+		})
+	})
+})
+
+func writeSpec(directory, file, contents string) {
+	Expect(os.WriteFile(filepath.Join(directory, file), []byte(contents), 0644)).To(Succeed())
+}
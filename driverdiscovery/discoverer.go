@@ -0,0 +1,151 @@
+package driverdiscovery
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/lager/v3"
+)
+
+//go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -generate
+//counterfeiter:generate -o ../dockerdriverfakes/fake_discoverer.go . Discoverer
+type Discoverer interface {
+	Discover(logger lager.Logger) ([]dockerdriver.DriverSpec, error)
+	Directories() []string
+}
+
+// NewDiscoverer scans pluginDirectories in the order given, so a driver found in
+// an earlier directory shadows one of the same name in a later directory.
+func NewDiscoverer(pluginDirectories ...string) Discoverer {
+	return &discoverer{directories: pluginDirectories}
+}
+
+type discoverer struct {
+	directories []string
+}
+
+func (d *discoverer) Directories() []string {
+	return d.directories
+}
+
+func (d *discoverer) Discover(logger lager.Logger) ([]dockerdriver.DriverSpec, error) {
+	logger = logger.Session("discover", lager.Data{"directories": d.directories})
+	logger.Debug("start")
+	defer logger.Debug("end")
+
+	names, err := d.driverNames(logger)
+	if err != nil {
+		return nil, err
+	}
+
+	specs := []dockerdriver.DriverSpec{}
+	for _, name := range names {
+		spec, found := d.lookup(logger, name)
+		if found {
+			specs = append(specs, *spec)
+		}
+	}
+	return specs, nil
+}
+
+// driverNames lists every name that has a .sock, .spec or .json file either
+// directly in a plugin directory or in a <name>/<name>.<ext> subdirectory.
+func (d *discoverer) driverNames(logger lager.Logger) ([]string, error) {
+	seen := map[string]bool{}
+	for _, directory := range d.directories {
+		entries, err := os.ReadDir(directory)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			logger.Error("failed-reading-directory", err, lager.Data{"directory": directory})
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() {
+				name := entry.Name()
+				for _, extension := range extensions {
+					if _, err := os.Stat(filepath.Join(directory, name, name+extension)); err == nil {
+						seen[name] = true
+						break
+					}
+				}
+				continue
+			}
+
+			extension := filepath.Ext(entry.Name())
+			if isSpecExtension(extension) {
+				seen[strings.TrimSuffix(entry.Name(), extension)] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// lookup follows docker's precedence: a socket in any directory wins, then
+// .spec and .json files directory by directory.
+func (d *discoverer) lookup(logger lager.Logger, name string) (*dockerdriver.DriverSpec, bool) {
+	for _, candidate := range d.candidates(name) {
+		info, err := os.Stat(filepath.Join(candidate.directory, candidate.file))
+		if err != nil {
+			continue
+		}
+		if candidate.extension == ".sock" && info.Mode()&os.ModeSocket == 0 {
+			continue
+		}
+
+		spec, err := dockerdriver.ReadDriverSpec(logger, name, candidate.directory, candidate.file)
+		if err != nil {
+			logger.Error("failed-reading-driver-spec", err, lager.Data{"directory": candidate.directory, "file": candidate.file})
+			return nil, false
+		}
+		return spec, true
+	}
+	return nil, false
+}
+
+type candidate struct {
+	directory string
+	file      string
+	extension string
+}
+
+var extensions = []string{".sock", ".spec", ".json"}
+
+func isSpecExtension(extension string) bool {
+	for _, e := range extensions {
+		if e == extension {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *discoverer) candidates(name string) []candidate {
+	var candidates []candidate
+	paths := func(directory, extension string) []candidate {
+		return []candidate{
+			{directory: directory, file: name + extension, extension: extension},
+			{directory: filepath.Join(directory, name), file: name + extension, extension: extension},
+		}
+	}
+
+	for _, directory := range d.directories {
+		candidates = append(candidates, paths(directory, ".sock")...)
+	}
+	for _, directory := range d.directories {
+		candidates = append(candidates, paths(directory, ".spec")...)
+		candidates = append(candidates, paths(directory, ".json")...)
+	}
+	return candidates
+}
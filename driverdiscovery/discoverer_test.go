@@ -0,0 +1,139 @@
+package driverdiscovery_test
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverdiscovery"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Discoverer", func() {
+	var (
+		testLogger  *lagertest.TestLogger
+		firstDir    string
+		secondDir   string
+		discoverer  driverdiscovery.Discoverer
+		specs       []dockerdriver.DriverSpec
+		discoverErr error
+	)
+
+	BeforeEach(func() {
+		testLogger = lagertest.NewTestLogger("discoverer-test")
+
+		var err error
+		firstDir, err = os.MkdirTemp("", "dd")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, firstDir)
+
+		secondDir, err = os.MkdirTemp("", "dd")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, secondDir)
+
+		discoverer = driverdiscovery.NewDiscoverer(firstDir, secondDir)
+	})
+
+	JustBeforeEach(func() {
+		specs, discoverErr = discoverer.Discover(testLogger)
+	})
+
+	Context("when the directories are empty", func() {
+		It("should find nothing", func() {
+			Expect(discoverErr).NotTo(HaveOccurred())
+			Expect(specs).To(BeEmpty())
+		})
+	})
+
+	Context("when a directory does not exist", func() {
+		BeforeEach(func() {
+			discoverer = driverdiscovery.NewDiscoverer(filepath.Join(firstDir, "missing"), secondDir)
+			writeFile(filepath.Join(secondDir, "some-driver.spec"), "http://127.0.0.1:7589")
+		})
+
+		It("should scan the others", func() {
+			Expect(discoverErr).NotTo(HaveOccurred())
+			Expect(specs).To(Equal([]dockerdriver.DriverSpec{{Name: "some-driver", Address: "http://127.0.0.1:7589"}}))
+		})
+	})
+
+	Context("when drivers use each layout", func() {
+		BeforeEach(func() {
+			writeFile(filepath.Join(firstDir, "spec-driver.spec"), "http://127.0.0.1:7589")
+			writeFile(filepath.Join(firstDir, "json-driver.json"), `{"Addr":"https://127.0.0.1:7590","UniqueVolumeIds":true}`)
+			listenUnix(filepath.Join(firstDir, "sock-driver.sock"))
+			Expect(os.Mkdir(filepath.Join(secondDir, "nested-driver"), 0755)).To(Succeed())
+			listenUnix(filepath.Join(secondDir, "nested-driver", "nested-driver.sock"))
+			writeFile(filepath.Join(firstDir, "README"), "not a driver")
+		})
+
+		It("should find all of them sorted by name", func() {
+			Expect(discoverErr).NotTo(HaveOccurred())
+			Expect(specs).To(Equal([]dockerdriver.DriverSpec{
+				{Name: "json-driver", Address: "https://127.0.0.1:7590", UniqueVolumeIds: true},
+				{Name: "nested-driver", Address: filepath.Join(secondDir, "nested-driver", "nested-driver.sock")},
+				{Name: "sock-driver", Address: filepath.Join(firstDir, "sock-driver.sock")},
+				{Name: "spec-driver", Address: "http://127.0.0.1:7589"},
+			}))
+		})
+	})
+
+	Context("when a driver has several specs", func() {
+		BeforeEach(func() {
+			writeFile(filepath.Join(firstDir, "some-driver.json"), `{"Addr":"http://json"}`)
+			writeFile(filepath.Join(firstDir, "some-driver.spec"), "http://spec")
+		})
+
+		It("should prefer the .spec to the .json", func() {
+			Expect(specs).To(HaveLen(1))
+			Expect(specs[0].Address).To(Equal("http://spec"))
+		})
+
+		Context("when there is also a socket in a later directory", func() {
+			BeforeEach(func() {
+				listenUnix(filepath.Join(secondDir, "some-driver.sock"))
+			})
+
+			It("should prefer the socket", func() {
+				Expect(specs).To(HaveLen(1))
+				Expect(specs[0].Address).To(Equal(filepath.Join(secondDir, "some-driver.sock")))
+			})
+		})
+
+		Context("when a later directory has a .spec too", func() {
+			BeforeEach(func() {
+				writeFile(filepath.Join(secondDir, "some-driver.spec"), "http://later-spec")
+			})
+
+			It("should prefer the earlier directory", func() {
+				Expect(specs).To(HaveLen(1))
+				Expect(specs[0].Address).To(Equal("http://spec"))
+			})
+		})
+	})
+
+	Context("when a .sock file is not a socket", func() {
+		BeforeEach(func() {
+			writeFile(filepath.Join(firstDir, "some-driver.sock"), "")
+			writeFile(filepath.Join(firstDir, "some-driver.spec"), "http://spec")
+		})
+
+		It("should fall back to the spec", func() {
+			Expect(specs).To(HaveLen(1))
+			Expect(specs[0].Address).To(Equal("http://spec"))
+		})
+	})
+})
+
+func writeFile(path, contents string) {
+	Expect(os.WriteFile(path, []byte(contents), 0644)).To(Succeed())
+}
+
+func listenUnix(path string) {
+	listener, err := net.Listen("unix", path)
+	Expect(err).NotTo(HaveOccurred())
+	DeferCleanup(listener.Close)
+}
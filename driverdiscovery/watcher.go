@@ -0,0 +1,204 @@
+package driverdiscovery
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/fsnotify/fsnotify"
+	"github.com/tedsuo/ifrit"
+)
+
+type EventType string
+
+const (
+	DriverAdded   EventType = "added"
+	DriverChanged EventType = "changed"
+	DriverRemoved EventType = "removed"
+)
+
+// Event carries the new spec for added and changed drivers, and the last
+// known spec for removed ones.
+type Event struct {
+	Type EventType
+	Spec dockerdriver.DriverSpec
+}
+
+type watcher struct {
+	logger     lager.Logger
+	discoverer Discoverer
+	events     chan<- Event
+}
+
+// NewWatcher returns a runner that emits an added event for every driver
+// already present once it is ready, and then an event for every driver that
+// is installed, changed or removed while it runs.
+func NewWatcher(logger lager.Logger, discoverer Discoverer, events chan<- Event) ifrit.Runner {
+	return &watcher{
+		logger:     logger,
+		discoverer: discoverer,
+		events:     events,
+	}
+}
+
+func (w *watcher) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	logger := w.logger.Session("driver-watcher")
+	logger.Info("start")
+	defer logger.Info("end")
+
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		logger.Error("failed-creating-watcher", err)
+		return err
+	}
+	defer fsWatcher.Close()
+
+	// plugin directories that don't exist yet, e.g. before docker has made
+	// /run/docker/plugins, are picked up when they are created
+	pending := map[string]bool{}
+	for _, directory := range w.discoverer.Directories() {
+		pending[directory] = true
+	}
+	w.watchPending(logger, fsWatcher, pending)
+
+	known := map[string]dockerdriver.DriverSpec{}
+	close(ready)
+
+	if !w.rescan(logger, known, signals) {
+		return nil
+	}
+
+	for {
+		select {
+		case <-signals:
+			return nil
+		case event, ok := <-fsWatcher.Events:
+			if !ok {
+				return nil
+			}
+			logger.Debug("fs-event", lager.Data{"event": event.String()})
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					if len(pending) > 0 {
+						w.watchPending(logger, fsWatcher, pending)
+					}
+					w.watch(logger, fsWatcher, event.Name)
+				}
+			}
+			if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+				if slices.Contains(w.discoverer.Directories(), event.Name) {
+					pending[event.Name] = true
+					w.watchPending(logger, fsWatcher, pending)
+				}
+			}
+			if !w.rescan(logger, known, signals) {
+				return nil
+			}
+		case err, ok := <-fsWatcher.Errors:
+			if !ok {
+				return nil
+			}
+			logger.Error("watch-error", err)
+		}
+	}
+}
+
+// watchPending watches the pending plugin directories that now exist, and
+// for the rest the closest parent that does, to hear when they are created.
+func (w *watcher) watchPending(logger lager.Logger, fsWatcher *fsnotify.Watcher, pending map[string]bool) {
+	for directory := range pending {
+		if w.watchTree(logger, fsWatcher, directory) {
+			delete(pending, directory)
+			continue
+		}
+		if parent, ok := closestExistingParent(directory); ok {
+			w.watch(logger, fsWatcher, parent)
+		}
+	}
+}
+
+// watchTree watches a plugin directory and the <name> subdirectories in it.
+// It returns false if the directory itself can't be watched.
+func (w *watcher) watchTree(logger lager.Logger, fsWatcher *fsnotify.Watcher, directory string) bool {
+	if !w.watch(logger, fsWatcher, directory) {
+		return false
+	}
+
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return true
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			w.watch(logger, fsWatcher, filepath.Join(directory, entry.Name()))
+		}
+	}
+	return true
+}
+
+func (w *watcher) watch(logger lager.Logger, fsWatcher *fsnotify.Watcher, directory string) bool {
+	if err := fsWatcher.Add(directory); err != nil {
+		logger.Info("not-watching-directory", lager.Data{"directory": directory, "error": err.Error()})
+		return false
+	}
+	return true
+}
+
+func closestExistingParent(directory string) (string, bool) {
+	for {
+		parent := filepath.Dir(directory)
+		if parent == directory {
+			return "", false
+		}
+		if info, err := os.Stat(parent); err == nil && info.IsDir() {
+			return parent, true
+		}
+		directory = parent
+	}
+}
+
+// rescan returns false if it was signalled while delivering events.
+func (w *watcher) rescan(logger lager.Logger, known map[string]dockerdriver.DriverSpec, signals <-chan os.Signal) bool {
+	specs, err := w.discoverer.Discover(logger)
+	if err != nil {
+		logger.Error("failed-discovering-drivers", err)
+		return true
+	}
+
+	current := map[string]dockerdriver.DriverSpec{}
+	var events []Event
+	for _, spec := range specs {
+		current[spec.Name] = spec
+		previous, found := known[spec.Name]
+		if !found {
+			events = append(events, Event{Type: DriverAdded, Spec: spec})
+		} else if !reflect.DeepEqual(previous, spec) {
+			events = append(events, Event{Type: DriverChanged, Spec: spec})
+		}
+	}
+	for name, spec := range known {
+		if _, found := current[name]; !found {
+			events = append(events, Event{Type: DriverRemoved, Spec: spec})
+		}
+	}
+
+	for name := range known {
+		delete(known, name)
+	}
+	for name, spec := range current {
+		known[name] = spec
+	}
+
+	for _, event := range events {
+		logger.Info("driver-"+string(event.Type), lager.Data{"driver": event.Spec.Name, "address": event.Spec.Address})
+		select {
+		case w.events <- event:
+		case <-signals:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,125 @@
+package driverdiscovery_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverdiscovery"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+	ginkgomon "github.com/tedsuo/ifrit/ginkgomon_v2"
+)
+
+var _ = Describe("Watcher", func() {
+	var (
+		testLogger *lagertest.TestLogger
+		pluginDir  string
+		events     chan driverdiscovery.Event
+		process    ifrit.Process
+	)
+
+	BeforeEach(func() {
+		testLogger = lagertest.NewTestLogger("watcher-test")
+
+		var err error
+		pluginDir, err = os.MkdirTemp("", "dw")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, pluginDir)
+
+		replaceFile(filepath.Join(pluginDir, "existing-driver.spec"), "http://existing")
+
+		events = make(chan driverdiscovery.Event, 10)
+	})
+
+	var watchedDir string
+
+	BeforeEach(func() {
+		watchedDir = ""
+	})
+
+	JustBeforeEach(func() {
+		if watchedDir == "" {
+			watchedDir = pluginDir
+		}
+		process = ginkgomon.Invoke(driverdiscovery.NewWatcher(testLogger, driverdiscovery.NewDiscoverer(watchedDir), events))
+	})
+
+	AfterEach(func() {
+		ginkgomon.Interrupt(process)
+	})
+
+	It("should report the drivers already installed", func() {
+		Eventually(events).Should(Receive(Equal(driverdiscovery.Event{
+			Type: driverdiscovery.DriverAdded,
+			Spec: dockerdriver.DriverSpec{Name: "existing-driver", Address: "http://existing"},
+		})))
+	})
+
+	Context("when the plugin directory doesn't exist yet", func() {
+		BeforeEach(func() {
+			watchedDir = filepath.Join(pluginDir, "docker", "plugins")
+		})
+
+		It("should report a driver installed once it is created", func() {
+			Expect(os.MkdirAll(watchedDir, 0755)).To(Succeed())
+			replaceFile(filepath.Join(watchedDir, "late-driver.spec"), "http://late")
+
+			Eventually(events).Should(Receive(Equal(driverdiscovery.Event{
+				Type: driverdiscovery.DriverAdded,
+				Spec: dockerdriver.DriverSpec{Name: "late-driver", Address: "http://late"},
+			})))
+		})
+	})
+
+	Context("once the existing drivers are reported", func() {
+		JustBeforeEach(func() {
+			Eventually(events).Should(Receive())
+		})
+
+		It("should report an installed driver", func() {
+			replaceFile(filepath.Join(pluginDir, "new-driver.spec"), "http://new")
+
+			Eventually(events).Should(Receive(Equal(driverdiscovery.Event{
+				Type: driverdiscovery.DriverAdded,
+				Spec: dockerdriver.DriverSpec{Name: "new-driver", Address: "http://new"},
+			})))
+		})
+
+		It("should report an installed driver in a subdirectory", func() {
+			Expect(os.Mkdir(filepath.Join(pluginDir, "nested-driver"), 0755)).To(Succeed())
+			replaceFile(filepath.Join(pluginDir, "nested-driver", "nested-driver.spec"), "http://nested")
+
+			Eventually(events).Should(Receive(Equal(driverdiscovery.Event{
+				Type: driverdiscovery.DriverAdded,
+				Spec: dockerdriver.DriverSpec{Name: "nested-driver", Address: "http://nested"},
+			})))
+		})
+
+		It("should report a changed driver", func() {
+			replaceFile(filepath.Join(pluginDir, "existing-driver.spec"), "http://upgraded")
+
+			Eventually(events).Should(Receive(Equal(driverdiscovery.Event{
+				Type: driverdiscovery.DriverChanged,
+				Spec: dockerdriver.DriverSpec{Name: "existing-driver", Address: "http://upgraded"},
+			})))
+		})
+
+		It("should report a removed driver", func() {
+			Expect(os.Remove(filepath.Join(pluginDir, "existing-driver.spec"))).To(Succeed())
+
+			Eventually(events).Should(Receive(Equal(driverdiscovery.Event{
+				Type: driverdiscovery.DriverRemoved,
+				Spec: dockerdriver.DriverSpec{Name: "existing-driver", Address: "http://existing"},
+			})))
+		})
+	})
+})
+
+// replaceFile writes via a rename so the watcher never sees a half written spec
+func replaceFile(path, contents string) {
+	writeFile(path+".tmp", contents)
+	Expect(os.Rename(path+".tmp", path)).To(Succeed())
+}
@@ -0,0 +1,220 @@
+package driverhttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/lager/v3"
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type CircuitState int
+
+const (
+	CircuitClosed CircuitState = iota
+	CircuitOpen
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(s))
+	}
+}
+
+// CircuitBreakerConfig makes remoteClient fail fast once a driver has stopped
+// responding, rather than have every caller wait for it to time out. Only
+// failures to get a response count; errors reported by the driver do not.
+type CircuitBreakerConfig struct {
+	// FailureThreshold is the number of consecutive failures that opens the
+	// circuit.
+	FailureThreshold int
+	// OpenTimeout is how long the circuit stays open before the next call
+	// probes the driver with Activate.
+	OpenTimeout time.Duration
+	// OnStateChange, if set, is called on every transition. It is called with
+	// the breaker locked, so it must not block or call back into the client.
+	OnStateChange func(from, to CircuitState)
+}
+
+func DefaultCircuitBreakerConfig() CircuitBreakerConfig {
+	return CircuitBreakerConfig{
+		FailureThreshold: 5,
+		OpenTimeout:      30 * time.Second,
+	}
+}
+
+func WithCircuitBreaker(config CircuitBreakerConfig) RemoteClientOption {
+	return func(r *remoteClient) {
+		r.breaker = newCircuitBreaker(config, r.clock)
+	}
+}
+
+// CircuitState reports the state of the client's circuit breaker; a client
+// without one is always closed.
+func (r *remoteClient) CircuitState() CircuitState {
+	if r.breaker == nil {
+		return CircuitClosed
+	}
+	return r.breaker.currentState()
+}
+
+type circuitBreaker struct {
+	config CircuitBreakerConfig
+	clock  clock.Clock
+
+	lock     sync.Mutex
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	// probing is set while a caller probes a half-open circuit
+	probing bool
+}
+
+func newCircuitBreaker(config CircuitBreakerConfig, clock clock.Clock) *circuitBreaker {
+	if config.FailureThreshold < 1 {
+		config.FailureThreshold = 1
+	}
+	return &circuitBreaker{
+		config: config,
+		clock:  clock,
+		state:  CircuitClosed,
+	}
+}
+
+func (b *circuitBreaker) currentState() CircuitState {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.state
+}
+
+// allow returns nil if a call may go ahead. Once the open timeout has passed,
+// the first caller through moves the circuit to half-open and runs probe;
+// everyone else keeps failing fast until the probe settles the state. A probe
+// abandoned because ctx is done settles nothing, so the next caller probes.
+func (b *circuitBreaker) allow(ctx context.Context, logger lager.Logger, probe func() error) error {
+	b.lock.Lock()
+	switch b.state {
+	case CircuitClosed:
+		b.lock.Unlock()
+		return nil
+	case CircuitOpen:
+		if b.clock.Since(b.openedAt) < b.config.OpenTimeout {
+			b.lock.Unlock()
+			return ErrCircuitOpen
+		}
+		b.transition(logger, CircuitHalfOpen)
+	default:
+		if b.probing {
+			b.lock.Unlock()
+			return ErrCircuitOpen
+		}
+	}
+	b.probing = true
+	b.lock.Unlock()
+
+	err := probe()
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.probing = false
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		logger.Info("circuit-breaker-probe-abandoned", lager.Data{"error": ctxErr.Error()})
+		return fmt.Errorf("%w: %w", ErrCircuitOpen, ctxErr)
+	}
+	if err != nil {
+		logger.Error("circuit-breaker-probe-failed", err)
+		b.open(logger)
+		return fmt.Errorf("%w: %w", ErrCircuitOpen, err)
+	}
+	b.failures = 0
+	b.transition(logger, CircuitClosed)
+	return nil
+}
+
+func (b *circuitBreaker) record(logger lager.Logger, failed bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if !failed {
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == CircuitClosed && b.failures >= b.config.FailureThreshold {
+		b.open(logger)
+	}
+}
+
+func (b *circuitBreaker) open(logger lager.Logger) {
+	b.openedAt = b.clock.Now()
+	b.transition(logger, CircuitOpen)
+}
+
+func (b *circuitBreaker) transition(logger lager.Logger, to CircuitState) {
+	from := b.state
+	if from == to {
+		return
+	}
+	b.state = to
+	logger.Info("circuit-breaker-state-changed", lager.Data{"from": from.String(), "to": to.String(), "failures": b.failures})
+
+	if b.config.OnStateChange != nil {
+		b.config.OnStateChange(from, to)
+	}
+}
+
+// isBreakerFailure is true for errors that suggest the driver is not
+// responding, including a proxy in front of it answering 502, 503 or 504. A
+// caller giving up on its own context says nothing about the driver.
+func isBreakerFailure(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var connErr *connectionError
+	if errors.As(err, &connErr) || errors.Is(err, ErrTimeout) {
+		return true
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		switch statusErr.StatusCode {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+	}
+	return false
+}
+
+// probe succeeds if the driver answers Activate at all, even with an error.
+func (r *remoteClient) probe(ctx context.Context, logger lager.Logger) func() error {
+	return func() error {
+		logger := logger.Session("circuit-breaker-probe")
+		probeCtx, cancel := r.timeouts.withTimeout(ctx, dockerdriver.ActivateRoute)
+		defer cancel()
+
+		_, err := r.doOnce(probeCtx, logger, newReqFactory(r.reqGen, dockerdriver.ActivateRoute, nil))
+		if timeoutErr := timeoutError(probeCtx); timeoutErr != nil {
+			return timeoutErr
+		}
+		if err != nil && isBreakerFailure(ctx, err) {
+			return err
+		}
+		return nil
+	}
+}
@@ -0,0 +1,181 @@
+package driverhttp_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/goshims/http_wrap/http_fake"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("CircuitBreaker", func() {
+	var (
+		testLogger  *lagertest.TestLogger
+		env         dockerdriver.Env
+		httpClient  *http_fake.FakeClient
+		fakeClock   *fakeclock.FakeClock
+		transitions []string
+		driver      interface {
+			dockerdriver.Driver
+			CircuitState() driverhttp.CircuitState
+		}
+	)
+
+	okResponse := func(body string) *http.Response {
+		return &http.Response{
+			StatusCode: driverhttp.StatusOK,
+			Body:       stringCloser{bytes.NewBufferString(body)},
+		}
+	}
+
+	BeforeEach(func() {
+		testLogger = lagertest.NewTestLogger("circuit-breaker-test")
+		env = driverhttp.NewHttpDriverEnv(testLogger, context.TODO())
+		httpClient = new(http_fake.FakeClient)
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+		transitions = nil
+
+		driver = driverhttp.NewRemoteClientWithClient("http://127.0.0.1:8080", nil, httpClient, fakeClock, driverhttp.WithCircuitBreaker(driverhttp.CircuitBreakerConfig{
+			FailureThreshold: 2,
+			OpenTimeout:      time.Minute,
+			OnStateChange: func(from, to driverhttp.CircuitState) {
+				transitions = append(transitions, from.String()+"->"+to.String())
+			},
+		}))
+	})
+
+	It("should start closed", func() {
+		Expect(driver.CircuitState()).To(Equal(driverhttp.CircuitClosed))
+	})
+
+	Context("when the driver cannot be reached", func() {
+		BeforeEach(func() {
+			httpClient.DoReturns(nil, fmt.Errorf("connection refused"))
+
+			driver.Path(env, dockerdriver.PathRequest{Name: "fake-volume"})
+			driver.Path(env, dockerdriver.PathRequest{Name: "fake-volume"})
+		})
+
+		It("should open after the failure threshold", func() {
+			Expect(driver.CircuitState()).To(Equal(driverhttp.CircuitOpen))
+			Expect(transitions).To(Equal([]string{"closed->open"}))
+			Expect(testLogger.Buffer()).To(gbytes.Say("circuit-breaker-state-changed"))
+		})
+
+		It("should fail fast without calling the driver", func() {
+			mountResponse := driver.Mount(env, dockerdriver.MountRequest{Name: "fake-volume"})
+
+			Expect(mountResponse.Err).To(Equal(driverhttp.ErrCircuitOpen.Error()))
+			Expect(httpClient.DoCallCount()).To(Equal(2))
+		})
+
+		Context("when the open timeout has passed", func() {
+			BeforeEach(func() {
+				fakeClock.Increment(time.Minute)
+			})
+
+			Context("and the driver has recovered", func() {
+				BeforeEach(func() {
+					httpClient.DoReturnsOnCall(2, okResponse(`{"Implements":["VolumeDriver"]}`), nil)
+					httpClient.DoReturnsOnCall(3, okResponse(`{"Mountpoint":"somePath"}`), nil)
+				})
+
+				It("should probe with activate, close, and make the call", func() {
+					mountResponse := driver.Mount(env, dockerdriver.MountRequest{Name: "fake-volume"})
+
+					Expect(mountResponse.Err).To(BeEmpty())
+					Expect(mountResponse.Mountpoint).To(Equal("somePath"))
+					Expect(httpClient.DoArgsForCall(2).URL.Path).To(Equal("/Plugin.Activate"))
+					Expect(httpClient.DoArgsForCall(3).URL.Path).To(Equal("/VolumeDriver.Mount"))
+					Expect(driver.CircuitState()).To(Equal(driverhttp.CircuitClosed))
+					Expect(transitions).To(Equal([]string{"closed->open", "open->half-open", "half-open->closed"}))
+				})
+			})
+
+			Context("and the driver is still unreachable", func() {
+				It("should reopen without making the call", func() {
+					mountResponse := driver.Mount(env, dockerdriver.MountRequest{Name: "fake-volume"})
+
+					Expect(mountResponse.Err).To(ContainSubstring(driverhttp.ErrCircuitOpen.Error()))
+					Expect(httpClient.DoCallCount()).To(Equal(3))
+					Expect(driver.CircuitState()).To(Equal(driverhttp.CircuitOpen))
+					Expect(transitions).To(Equal([]string{"closed->open", "open->half-open", "half-open->open"}))
+				})
+			})
+
+			Context("and the caller gives up during the probe", func() {
+				It("should stay half-open and let the next caller probe", func() {
+					ctx, cancel := context.WithCancel(context.Background())
+					httpClient.DoStub = func(*http.Request) (*http.Response, error) {
+						cancel()
+						return nil, context.Canceled
+					}
+
+					mountResponse := driver.Mount(driverhttp.NewHttpDriverEnv(testLogger, ctx), dockerdriver.MountRequest{Name: "fake-volume"})
+					Expect(mountResponse.Err).To(ContainSubstring(driverhttp.ErrCircuitOpen.Error()))
+					Expect(driver.CircuitState()).To(Equal(driverhttp.CircuitHalfOpen))
+
+					httpClient.DoStub = nil
+					httpClient.DoReturnsOnCall(3, okResponse(`{"Implements":["VolumeDriver"]}`), nil)
+					httpClient.DoReturnsOnCall(4, okResponse(`{"Mountpoint":"somePath"}`), nil)
+
+					Expect(driver.Mount(env, dockerdriver.MountRequest{Name: "fake-volume"}).Mountpoint).To(Equal("somePath"))
+					Expect(transitions).To(Equal([]string{"closed->open", "open->half-open", "half-open->closed"}))
+				})
+			})
+		})
+	})
+
+	Context("when a proxy in front of the driver reports it unavailable", func() {
+		It("should open after the failure threshold", func() {
+			httpClient.DoStub = func(*http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusServiceUnavailable,
+					Status:     "503 Service Unavailable",
+					Body:       stringCloser{bytes.NewBufferString("no healthy upstream")},
+				}, nil
+			}
+
+			driver.Path(env, dockerdriver.PathRequest{Name: "fake-volume"})
+			driver.Path(env, dockerdriver.PathRequest{Name: "fake-volume"})
+			Expect(driver.CircuitState()).To(Equal(driverhttp.CircuitOpen))
+		})
+	})
+
+	Context("when the driver reports errors", func() {
+		BeforeEach(func() {
+			httpClient.DoStub = func(*http.Request) (*http.Response, error) {
+				return okResponse(`{"Err":"volume does not exist"}`), nil
+			}
+		})
+
+		It("should stay closed", func() {
+			for i := 0; i < 3; i++ {
+				Expect(driver.Path(env, dockerdriver.PathRequest{Name: "fake-volume"}).Err).To(Equal("volume does not exist"))
+			}
+			Expect(driver.CircuitState()).To(Equal(driverhttp.CircuitClosed))
+		})
+	})
+
+	Context("when a request succeeds between failures", func() {
+		It("should not count the failures as consecutive", func() {
+			httpClient.DoReturnsOnCall(0, nil, fmt.Errorf("connection refused"))
+			httpClient.DoReturnsOnCall(1, okResponse(`{"Mountpoint":"somePath"}`), nil)
+			httpClient.DoReturnsOnCall(2, nil, fmt.Errorf("connection refused"))
+
+			for i := 0; i < 3; i++ {
+				driver.Path(env, dockerdriver.PathRequest{Name: "fake-volume"})
+			}
+			Expect(driver.CircuitState()).To(Equal(driverhttp.CircuitClosed))
+		})
+	})
+})
@@ -0,0 +1,257 @@
+package driverhttp
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/drivermetrics"
+	"code.cloudfoundry.org/lager/v3"
+	"code.cloudfoundry.org/tlsconfig"
+	"github.com/tedsuo/ifrit"
+)
+
+const (
+	TransportTCP  = "tcp"
+	TransportUnix = "unix"
+
+	defaultShutdownTimeout = 10 * time.Second
+
+	// MetricsPath is where a server with a MetricsRegistry serves its metrics.
+	MetricsPath = "/metrics"
+)
+
+type DriverServerConfig struct {
+	DriverName string
+	// Transport is TransportTCP or TransportUnix; it defaults to TransportTCP.
+	Transport string
+	// ListenAddress is a host:port for tcp or a socket path for unix. A unix
+	// server with no listen address listens on <DriversPath>/<DriverName>.sock,
+	// which docker treats as the spec itself.
+	ListenAddress string
+	// DriversPath is the plugins directory the spec is written to. No spec is
+	// written when it is empty.
+	DriversPath string
+	// JSONSpec writes a .json spec rather than a .spec file. It is implied when
+	// ClientTLS or UniqueVolumeIds is set, since a .spec file cannot carry them.
+	JSONSpec        bool
+	UniqueVolumeIds bool
+
+	// ServerTLS enables TLS (with client authentication) on a tcp listener. A
+	// server that writes a spec must also set ClientTLS.
+	ServerTLS *ServerTLSConfig
+	// ClientTLS is advertised in the .json spec for clients to connect with.
+	ClientTLS *dockerdriver.TLSConfig
+
+	ShutdownTimeout time.Duration
+
+	HandlerOptions []HandlerOption
+	// MetricsRegistry, if set, records the handler's metrics and is served
+	// in the Prometheus text format on MetricsPath.
+	MetricsRegistry *drivermetrics.Registry
+}
+
+type ServerTLSConfig struct {
+	CAFile   string
+	CertFile string
+	KeyFile  string
+}
+
+type driverServer struct {
+	logger  lager.Logger
+	config  DriverServerConfig
+	handler http.Handler
+}
+
+func NewDriverServer(logger lager.Logger, config DriverServerConfig, driver dockerdriver.Driver) (ifrit.Runner, error) {
+	if config.DriverName == "" {
+		return nil, errors.New("driver name is required")
+	}
+	if config.Transport == "" {
+		config.Transport = TransportTCP
+	}
+	if config.Transport != TransportTCP && config.Transport != TransportUnix {
+		return nil, fmt.Errorf("unsupported transport: %q", config.Transport)
+	}
+	if config.Transport == TransportUnix && config.ListenAddress == "" {
+		if config.DriversPath == "" {
+			return nil, errors.New("a unix server needs a listen address or a drivers path")
+		}
+		config.ListenAddress = filepath.Join(config.DriversPath, config.DriverName+".sock")
+	}
+	// the server demands client certificates, and only a .json spec can tell
+	// clients which to use
+	if config.Transport == TransportTCP && config.ServerTLS != nil && config.ClientTLS == nil && config.DriversPath != "" {
+		return nil, errors.New("a tls server that writes a spec needs client tls to advertise")
+	}
+	if config.ShutdownTimeout == 0 {
+		config.ShutdownTimeout = defaultShutdownTimeout
+	}
+
+	handlerOptions := config.HandlerOptions
+	if config.MetricsRegistry != nil {
+		handlerOptions = append([]HandlerOption{WithHandlerMetrics(config.MetricsRegistry, config.DriverName)}, handlerOptions...)
+	}
+
+	handler, err := NewHandler(logger, driver, handlerOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.MetricsRegistry != nil {
+		mux := http.NewServeMux()
+		mux.Handle(MetricsPath, config.MetricsRegistry.Handler())
+		mux.Handle("/", handler)
+		handler = mux
+	}
+
+	return &driverServer{
+		logger:  logger,
+		config:  config,
+		handler: handler,
+	}, nil
+}
+
+func (d *driverServer) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	logger := d.logger.Session("driver-server", lager.Data{"transport": d.config.Transport, "address": d.config.ListenAddress})
+
+	listener, err := d.listen(logger)
+	if err != nil {
+		return err
+	}
+
+	specPath, err := d.writeSpec(logger, listener.Addr())
+	if err != nil {
+		listener.Close()
+		return err
+	}
+	defer d.cleanup(logger, specPath)
+
+	server := &http.Server{Handler: d.handler}
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- server.Serve(listener)
+	}()
+
+	close(ready)
+	logger.Info("started")
+
+	select {
+	case err := <-errChan:
+		logger.Error("serve-failed", err)
+		return err
+	case signal := <-signals:
+		logger.Info("signalled", lager.Data{"signal": signal.String()})
+
+		ctx, cancel := context.WithTimeout(context.Background(), d.config.ShutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			logger.Error("failed-graceful-shutdown", err)
+			server.Close()
+		}
+	}
+
+	logger.Info("stopped")
+	return nil
+}
+
+func (d *driverServer) listen(logger lager.Logger) (net.Listener, error) {
+	if d.config.Transport == TransportUnix {
+		if err := os.MkdirAll(filepath.Dir(d.config.ListenAddress), 0755); err != nil {
+			logger.Error("failed-creating-socket-directory", err)
+			return nil, err
+		}
+		// a socket left behind by a driver that was killed would stop us listening
+		if err := os.Remove(d.config.ListenAddress); err != nil && !os.IsNotExist(err) {
+			logger.Error("failed-removing-stale-socket", err)
+			return nil, err
+		}
+	}
+
+	listener, err := net.Listen(d.config.Transport, d.config.ListenAddress)
+	if err != nil {
+		logger.Error("failed-listening", err)
+		return nil, err
+	}
+
+	if d.config.Transport == TransportTCP && d.config.ServerTLS != nil {
+		tlsConfig, err := d.serverTLSConfig()
+		if err != nil {
+			logger.Error("failed-building-tls-config", err)
+			listener.Close()
+			return nil, err
+		}
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+
+	return listener, nil
+}
+
+func (d *driverServer) serverTLSConfig() (*tls.Config, error) {
+	serverTLS := d.config.ServerTLS
+	return tlsconfig.Build(
+		tlsconfig.WithInternalServiceDefaults(),
+		tlsconfig.WithIdentityFromFile(serverTLS.CertFile, serverTLS.KeyFile),
+	).Server(tlsconfig.WithClientAuthenticationFromFile(serverTLS.CAFile))
+}
+
+// writeSpec returns the path of the spec it wrote, or "" when no spec file is
+// needed.
+func (d *driverServer) writeSpec(logger lager.Logger, addr net.Addr) (string, error) {
+	if d.config.DriversPath == "" {
+		return "", nil
+	}
+
+	var address string
+	if d.config.Transport == TransportUnix {
+		if filepath.Dir(d.config.ListenAddress) == filepath.Clean(d.config.DriversPath) &&
+			filepath.Base(d.config.ListenAddress) == d.config.DriverName+".sock" {
+			return "", nil
+		}
+		address = "unix://" + d.config.ListenAddress
+	} else {
+		scheme := "http"
+		if d.config.ServerTLS != nil {
+			scheme = "https"
+		}
+		address = scheme + "://" + addr.String()
+	}
+
+	extension := "spec"
+	if d.config.JSONSpec || d.config.ClientTLS != nil || d.config.UniqueVolumeIds {
+		extension = "json"
+	}
+	spec := dockerdriver.DriverSpec{
+		Name:            d.config.DriverName,
+		Address:         address,
+		TLSConfig:       d.config.ClientTLS,
+		UniqueVolumeIds: d.config.UniqueVolumeIds,
+	}
+
+	logger.Info("writing-spec-file", lager.Data{"location": d.config.DriversPath, "address": address, "extension": extension})
+	if err := dockerdriver.WriteDriverSpecFile(logger, d.config.DriversPath, spec, extension); err != nil {
+		return "", err
+	}
+
+	return filepath.Join(d.config.DriversPath, d.config.DriverName+"."+extension), nil
+}
+
+func (d *driverServer) cleanup(logger lager.Logger, specPath string) {
+	if specPath != "" {
+		if err := os.Remove(specPath); err != nil && !os.IsNotExist(err) {
+			logger.Error("failed-removing-spec-file", err, lager.Data{"spec": specPath})
+		}
+	}
+	if d.config.Transport == TransportUnix {
+		if err := os.Remove(d.config.ListenAddress); err != nil && !os.IsNotExist(err) {
+			logger.Error("failed-removing-socket", err)
+		}
+	}
+}
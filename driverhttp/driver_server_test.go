@@ -0,0 +1,190 @@
+package driverhttp_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/dockerdriverfakes"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/dockerdriver/drivermetrics"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/tedsuo/ifrit"
+	ginkgomon "github.com/tedsuo/ifrit/ginkgomon_v2"
+)
+
+var _ = Describe("DriverServer", func() {
+	var (
+		testLogger  *lagertest.TestLogger
+		fakeDriver  *dockerdriverfakes.FakeDriver
+		driversPath string
+		config      driverhttp.DriverServerConfig
+		process     ifrit.Process
+		env         dockerdriver.Env
+	)
+
+	BeforeEach(func() {
+		testLogger = lagertest.NewTestLogger("driver-server-test")
+		env = driverhttp.NewHttpDriverEnv(testLogger, context.TODO())
+
+		fakeDriver = &dockerdriverfakes.FakeDriver{}
+		fakeDriver.ActivateReturns(dockerdriver.ActivateResponse{Implements: []string{"VolumeDriver"}})
+
+		var err error
+		driversPath, err = os.MkdirTemp("", "ds")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, driversPath)
+
+		config = driverhttp.DriverServerConfig{
+			DriverName:    "some-driver",
+			DriversPath:   driversPath,
+			ListenAddress: "127.0.0.1:0",
+		}
+	})
+
+	JustBeforeEach(func() {
+		runner, err := driverhttp.NewDriverServer(testLogger, config, fakeDriver)
+		Expect(err).NotTo(HaveOccurred())
+
+		process = ginkgomon.Invoke(runner)
+	})
+
+	AfterEach(func() {
+		ginkgomon.Interrupt(process)
+	})
+
+	It("should log that the server started", func() {
+		Expect(testLogger.Buffer()).To(gbytes.Say("driver-server.started"))
+	})
+
+	Context("when the transport is tcp", func() {
+		It("should write a spec that clients can connect with", func() {
+			spec, err := dockerdriver.ReadDriverSpec(testLogger, "some-driver", driversPath, "some-driver.spec")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(spec.Address).To(HavePrefix("http://127.0.0.1:"))
+
+			client, err := driverhttp.NewRemoteClientFromSpec(spec)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(client.Activate(env).Implements).To(Equal([]string{"VolumeDriver"}))
+		})
+
+		It("should remove the spec on shutdown", func() {
+			specPath := filepath.Join(driversPath, "some-driver.spec")
+			Expect(specPath).To(BeAnExistingFile())
+
+			ginkgomon.Interrupt(process)
+			Expect(specPath).NotTo(BeAnExistingFile())
+		})
+
+		Context("when a metrics registry is configured", func() {
+			BeforeEach(func() {
+				config.MetricsRegistry = drivermetrics.NewRegistry()
+			})
+
+			It("should serve the driver's metrics", func() {
+				spec, err := dockerdriver.ReadDriverSpec(testLogger, "some-driver", driversPath, "some-driver.spec")
+				Expect(err).NotTo(HaveOccurred())
+
+				client, err := driverhttp.NewRemoteClientFromSpec(spec)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(client.Activate(env).Implements).To(Equal([]string{"VolumeDriver"}))
+
+				response, err := http.Get(spec.Address + driverhttp.MetricsPath)
+				Expect(err).NotTo(HaveOccurred())
+				defer response.Body.Close()
+
+				body, err := io.ReadAll(response.Body)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(body)).To(ContainSubstring(`dockerdriver_handler_requests_total{driver="some-driver",route="activate"} 1`))
+			})
+		})
+
+		Context("when a json spec is requested", func() {
+			BeforeEach(func() {
+				config.JSONSpec = true
+				config.UniqueVolumeIds = true
+			})
+
+			It("should write a json spec", func() {
+				spec, err := dockerdriver.ReadDriverSpec(testLogger, "some-driver", driversPath, "some-driver.json")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(spec.Address).To(HavePrefix("http://127.0.0.1:"))
+				Expect(spec.UniqueVolumeIds).To(BeTrue())
+			})
+		})
+	})
+
+	Context("when the transport is unix", func() {
+		BeforeEach(func() {
+			config.Transport = driverhttp.TransportUnix
+			config.ListenAddress = ""
+		})
+
+		Context("when no listen address is given", func() {
+			It("should listen on a socket in the drivers path", func() {
+				socketPath := filepath.Join(driversPath, "some-driver.sock")
+
+				client, err := driverhttp.NewRemoteClient(socketPath, nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(client.Activate(env).Implements).To(Equal([]string{"VolumeDriver"}))
+			})
+
+			It("should remove the socket on shutdown", func() {
+				socketPath := filepath.Join(driversPath, "some-driver.sock")
+				Expect(socketPath).To(BeAnExistingFile())
+
+				ginkgomon.Interrupt(process)
+				Expect(socketPath).NotTo(BeAnExistingFile())
+			})
+		})
+
+		Context("when the socket is outside the drivers path", func() {
+			var socketPath string
+
+			BeforeEach(func() {
+				socketDir, err := os.MkdirTemp("", "ds")
+				Expect(err).NotTo(HaveOccurred())
+				DeferCleanup(os.RemoveAll, socketDir)
+
+				socketPath = filepath.Join(socketDir, "driver.sock")
+				config.ListenAddress = socketPath
+			})
+
+			It("should write a spec pointing at the socket", func() {
+				spec, err := dockerdriver.ReadDriverSpec(testLogger, "some-driver", driversPath, "some-driver.spec")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(spec.Address).To(Equal("unix://" + socketPath))
+
+				client, err := driverhttp.NewRemoteClientFromSpec(spec)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(client.Activate(env).Implements).To(Equal([]string{"VolumeDriver"}))
+			})
+		})
+	})
+})
+
+var _ = Describe("NewDriverServer", func() {
+	It("should reject an unknown transport", func() {
+		_, err := driverhttp.NewDriverServer(lagertest.NewTestLogger("test"), driverhttp.DriverServerConfig{DriverName: "some-driver", Transport: "udp"}, &dockerdriverfakes.FakeDriver{})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should require a driver name", func() {
+		_, err := driverhttp.NewDriverServer(lagertest.NewTestLogger("test"), driverhttp.DriverServerConfig{}, &dockerdriverfakes.FakeDriver{})
+		Expect(err).To(HaveOccurred())
+	})
+	It("should reject a tls server whose spec can't tell clients how to connect", func() {
+		_, err := driverhttp.NewDriverServer(lagertest.NewTestLogger("test"), driverhttp.DriverServerConfig{
+			DriverName:  "some-driver",
+			DriversPath: GinkgoT().TempDir(),
+			ServerTLS:   &driverhttp.ServerTLSConfig{CAFile: "ca.crt", CertFile: "server.crt", KeyFile: "server.key"},
+		}, &dockerdriverfakes.FakeDriver{})
+		Expect(err).To(MatchError(ContainSubstring("needs client tls")))
+	})
+})
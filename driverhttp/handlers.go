@@ -10,6 +10,7 @@ import (
 
 	cf_http_handlers "code.cloudfoundry.org/cfhttp/v2/handlers"
 	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/drivertracing"
 	"code.cloudfoundry.org/lager/v3"
 	"github.com/tedsuo/rata"
 )
@@ -39,31 +40,15 @@ func EnvWithContext(ctx context.Context, env dockerdriver.Env) dockerdriver.Env
 	return &voldriverEnv{env.Logger(), ctx}
 }
 
+// EnvWithMonitor returns an Env whose context is cancelled when the request's
+// is, which the server does when the client goes away or the handler returns.
+// res is no longer used; it is kept for existing callers.
 func EnvWithMonitor(logger lager.Logger, ctx context.Context, res http.ResponseWriter) dockerdriver.Env {
-	logger = logger.Session("with-cancel")
+	logger = logger.Session("with-cancel", requestIDLogData(ctx))
 	logger.Debug("start")
 	defer logger.Debug("end")
 
-	cancelCtx, cancel := context.WithCancel(ctx)
-
-	env := NewHttpDriverEnv(logger, cancelCtx)
-
-	//lint:ignore SA1019 "too lazy to fix right now"
-	if closer, ok := res.(http.CloseNotifier); ok {
-		// Note: make calls in this thread to ensure reference on context
-		doneOrTimeoutChannel := ctx.Done()
-		cancelChannel := closer.CloseNotify()
-		go func() {
-			select {
-			case <-doneOrTimeoutChannel:
-			case <-cancelChannel:
-				logger.Debug("signalling-cancel")
-				cancel()
-			}
-		}()
-	}
-	_ = cancel
-	return env
+	return NewHttpDriverEnv(logger, ctx)
 }
 
 // At present, Docker ignores HTTP status codes, and requires errors to be returned in the response body.  To
@@ -73,36 +58,62 @@ const (
 	StatusOK                  = http.StatusOK
 )
 
-func NewHandler(logger lager.Logger, client dockerdriver.Driver) (http.Handler, error) {
+type handlerConfig struct {
+	redactUnsafeErrors  bool
+	rollbackMounts      bool
+	middleware          []Middleware
+	maxRequestBodyBytes int64
+	metrics             *requestMetrics
+	tracer              *drivertracing.Tracer
+}
+
+type HandlerOption func(*handlerConfig)
+
+func NewHandler(logger lager.Logger, client dockerdriver.Driver, opts ...HandlerOption) (http.Handler, error) {
 	logger = logger.Session("server")
 	logger.Info("start")
 	defer logger.Info("end")
 
+	config := handlerConfig{maxRequestBodyBytes: DefaultMaxRequestBodyBytes}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	if config.metrics != nil {
+		client = newInstrumentedDriver(client, config.metrics)
+	}
+	if config.redactUnsafeErrors {
+		client = newRedactingDriver(client)
+	}
+
 	var handlers = rata.Handlers{
 		dockerdriver.ActivateRoute:     newActivateHandler(logger, client),
 		dockerdriver.GetRoute:          newGetHandler(logger, client),
 		dockerdriver.ListRoute:         newListHandler(logger, client),
 		dockerdriver.PathRoute:         newPathHandler(logger, client),
 		dockerdriver.CreateRoute:       newCreateHandler(logger, client),
-		dockerdriver.MountRoute:        newMountHandler(logger, client),
+		dockerdriver.MountRoute:        newMountHandler(logger, client, config.rollbackMounts),
 		dockerdriver.UnmountRoute:      newUnmountHandler(logger, client),
 		dockerdriver.RemoveRoute:       newRemoveHandler(logger, client),
 		dockerdriver.CapabilitiesRoute: newCapabilitiesHandler(logger, client),
 	}
+	for route, handler := range handlers {
+		handlers[route] = config.chain(logger, route, handler)
+	}
 
 	return rata.NewRouter(dockerdriver.Routes, handlers)
 }
 
 func newActivateHandler(logger lager.Logger, client dockerdriver.Driver) http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
-		logger := logger.Session("handle-activate")
+		logger := logger.Session("handle-activate", requestLogData(req))
 		logger.Debug("start")
 		defer logger.Debug("end")
 
 		activateResponse := client.Activate(EnvWithMonitor(logger, req.Context(), w))
 		if activateResponse.Err != "" {
 			logger.Error("failed-activating-driver", fmt.Errorf("%s", activateResponse.Err))
-			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, activateResponse)
+			writeErrorResponse(w, req, activateResponse)
 			return
 		}
 
@@ -113,28 +124,28 @@ func newActivateHandler(logger lager.Logger, client dockerdriver.Driver) http.Ha
 
 func newGetHandler(logger lager.Logger, client dockerdriver.Driver) http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
-		logger := logger.Session("handle-get")
+		logger := logger.Session("handle-get", requestLogData(req))
 		logger.Info("start")
 		defer logger.Info("end")
 
 		body, err := io.ReadAll(req.Body)
 		if err != nil {
 			logger.Error("failed-reading-get-request-body", err)
-			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, dockerdriver.MountResponse{Err: err.Error()})
+			writeErrorResponse(w, req, dockerdriver.MountResponse{Err: err.Error()})
 			return
 		}
 
 		var getRequest dockerdriver.GetRequest
 		if err = json.Unmarshal(body, &getRequest); err != nil {
 			logger.Error("failed-unmarshalling-get-request-body", err)
-			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, dockerdriver.GetResponse{Err: err.Error()})
+			writeErrorResponse(w, req, dockerdriver.GetResponse{Err: err.Error()})
 			return
 		}
 
 		getResponse := client.Get(EnvWithMonitor(logger, req.Context(), w), getRequest)
 		if getResponse.Err != "" {
 			logger.Error("failed-getting-volume", err, lager.Data{"volume": getRequest.Name})
-			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, getResponse)
+			writeErrorResponse(w, req, getResponse)
 			return
 		}
 
@@ -144,14 +155,14 @@ func newGetHandler(logger lager.Logger, client dockerdriver.Driver) http.Handler
 
 func newListHandler(logger lager.Logger, client dockerdriver.Driver) http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
-		logger := logger.Session("handle-list")
+		logger := logger.Session("handle-list", requestLogData(req))
 		logger.Info("start")
 		defer logger.Info("end")
 
 		listResponse := client.List(EnvWithMonitor(logger, req.Context(), w))
 		if listResponse.Err != "" {
 			logger.Error("failed-listing-volumes", fmt.Errorf("%s", listResponse.Err))
-			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, listResponse)
+			writeErrorResponse(w, req, listResponse)
 			return
 		}
 
@@ -161,28 +172,28 @@ func newListHandler(logger lager.Logger, client dockerdriver.Driver) http.Handle
 
 func newPathHandler(logger lager.Logger, client dockerdriver.Driver) http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
-		logger := logger.Session("handle-path")
+		logger := logger.Session("handle-path", requestLogData(req))
 		logger.Info("start")
 		defer logger.Info("end")
 
 		body, err := io.ReadAll(req.Body)
 		if err != nil {
 			logger.Error("failed-reading-path-request-body", err)
-			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, dockerdriver.MountResponse{Err: err.Error()})
+			writeErrorResponse(w, req, dockerdriver.MountResponse{Err: err.Error()})
 			return
 		}
 
 		var pathRequest dockerdriver.PathRequest
 		if err = json.Unmarshal(body, &pathRequest); err != nil {
 			logger.Error("failed-unmarshalling-path-request-body", err)
-			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, dockerdriver.GetResponse{Err: err.Error()})
+			writeErrorResponse(w, req, dockerdriver.GetResponse{Err: err.Error()})
 			return
 		}
 
 		pathResponse := client.Path(EnvWithMonitor(logger, req.Context(), w), pathRequest)
 		if pathResponse.Err != "" {
 			logger.Error("failed-activating-driver", fmt.Errorf("%s", pathResponse.Err))
-			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, pathResponse)
+			writeErrorResponse(w, req, pathResponse)
 			return
 		}
 
@@ -192,7 +203,7 @@ func newPathHandler(logger lager.Logger, client dockerdriver.Driver) http.Handle
 
 func newCapabilitiesHandler(logger lager.Logger, client dockerdriver.Driver) http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
-		logger := logger.Session("handle-capabilities")
+		logger := logger.Session("handle-capabilities", requestLogData(req))
 		logger.Info("start")
 		defer logger.Info("end")
 
@@ -204,28 +215,28 @@ func newCapabilitiesHandler(logger lager.Logger, client dockerdriver.Driver) htt
 
 func newCreateHandler(logger lager.Logger, client dockerdriver.Driver) http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
-		logger := logger.Session("handle-create")
+		logger := logger.Session("handle-create", requestLogData(req))
 		logger.Info("start")
 		defer logger.Info("end")
 
 		body, err := io.ReadAll(req.Body)
 		if err != nil {
 			logger.Error("failed-reading-create-request-body", err)
-			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, dockerdriver.ErrorResponse{Err: err.Error()})
+			writeErrorResponse(w, req, dockerdriver.ErrorResponse{Err: err.Error()})
 			return
 		}
 
 		var createRequest dockerdriver.CreateRequest
 		if err = json.Unmarshal(body, &createRequest); err != nil {
 			logger.Error("failed-unmarshalling-create-request-body", err)
-			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, dockerdriver.ErrorResponse{Err: err.Error()})
+			writeErrorResponse(w, req, dockerdriver.ErrorResponse{Err: err.Error()})
 			return
 		}
 
 		createResponse := client.Create(EnvWithMonitor(logger, req.Context(), w), createRequest)
 		if createResponse.Err != "" {
 			logger.Error("failed-creating-volume", errors.New(createResponse.Err))
-			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, createResponse)
+			writeErrorResponse(w, req, createResponse)
 			return
 		}
 
@@ -233,30 +244,36 @@ func newCreateHandler(logger lager.Logger, client dockerdriver.Driver) http.Hand
 	}
 }
 
-func newMountHandler(logger lager.Logger, client dockerdriver.Driver) http.HandlerFunc {
+func newMountHandler(logger lager.Logger, client dockerdriver.Driver, rollbackMounts bool) http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
-		logger := logger.Session("handle-mount")
+		logger := logger.Session("handle-mount", requestLogData(req))
 		logger.Info("start")
 		defer logger.Info("end")
 
 		body, err := io.ReadAll(req.Body)
 		if err != nil {
 			logger.Error("failed-reading-mount-request-body", err)
-			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, dockerdriver.MountResponse{Err: err.Error()})
+			writeErrorResponse(w, req, dockerdriver.MountResponse{Err: err.Error()})
 			return
 		}
 
 		var mountRequest dockerdriver.MountRequest
 		if err = json.Unmarshal(body, &mountRequest); err != nil {
 			logger.Error("failed-unmarshalling-mount-request-body", err)
-			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, dockerdriver.MountResponse{Err: err.Error()})
+			writeErrorResponse(w, req, dockerdriver.MountResponse{Err: err.Error()})
 			return
 		}
 
 		mountResponse := client.Mount(EnvWithMonitor(logger, req.Context(), w), mountRequest)
 		if mountResponse.Err != "" {
-			logger.Error("failed-mounting-volume", errors.New(mountResponse.Err), lager.Data{"volume": mountRequest.Name})
-			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, mountResponse)
+			logger.Error("failed-mounting-volume", errors.New(mountResponse.Err), lager.Data{"volume": mountRequest.Name, "id": mountRequest.ID})
+			writeErrorResponse(w, req, mountResponse)
+			return
+		}
+
+		if rollbackMounts && req.Context().Err() != nil {
+			rollbackMount(logger, req, client, mountRequest)
+			writeErrorResponse(w, req, dockerdriver.MountResponse{Err: "mount abandoned by the caller and rolled back"})
 			return
 		}
 
@@ -266,28 +283,28 @@ func newMountHandler(logger lager.Logger, client dockerdriver.Driver) http.Handl
 
 func newUnmountHandler(logger lager.Logger, client dockerdriver.Driver) http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
-		logger := logger.Session("handle-unmount")
+		logger := logger.Session("handle-unmount", requestLogData(req))
 		logger.Info("start")
 		defer logger.Info("end")
 
 		body, err := io.ReadAll(req.Body)
 		if err != nil {
 			logger.Error("failed-reading-unmount-request-body", err)
-			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, dockerdriver.ErrorResponse{Err: err.Error()})
+			writeErrorResponse(w, req, dockerdriver.ErrorResponse{Err: err.Error()})
 			return
 		}
 
 		var unmountRequest dockerdriver.UnmountRequest
 		if err = json.Unmarshal(body, &unmountRequest); err != nil {
 			logger.Error("failed-unmarshalling-unmount-request-body", err)
-			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, dockerdriver.ErrorResponse{Err: err.Error()})
+			writeErrorResponse(w, req, dockerdriver.ErrorResponse{Err: err.Error()})
 			return
 		}
 
 		unmountResponse := client.Unmount(EnvWithMonitor(logger, req.Context(), w), unmountRequest)
 		if unmountResponse.Err != "" {
-			logger.Error("failed-unmount-volume", errors.New(unmountResponse.Err), lager.Data{"volume": unmountRequest.Name})
-			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, unmountResponse)
+			logger.Error("failed-unmount-volume", errors.New(unmountResponse.Err), lager.Data{"volume": unmountRequest.Name, "id": unmountRequest.ID})
+			writeErrorResponse(w, req, unmountResponse)
 			return
 		}
 
@@ -297,28 +314,28 @@ func newUnmountHandler(logger lager.Logger, client dockerdriver.Driver) http.Han
 
 func newRemoveHandler(logger lager.Logger, client dockerdriver.Driver) http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
-		logger := logger.Session("handle-remove")
+		logger := logger.Session("handle-remove", requestLogData(req))
 		logger.Info("start")
 		defer logger.Info("end")
 
 		body, err := io.ReadAll(req.Body)
 		if err != nil {
 			logger.Error("failed-reading-remove-request-body", err)
-			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, dockerdriver.ErrorResponse{Err: err.Error()})
+			writeErrorResponse(w, req, dockerdriver.ErrorResponse{Err: err.Error()})
 			return
 		}
 
 		var removeRequest dockerdriver.RemoveRequest
 		if err = json.Unmarshal(body, &removeRequest); err != nil {
 			logger.Error("failed-unmarshalling-unmount-request-body", err)
-			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, dockerdriver.ErrorResponse{Err: err.Error()})
+			writeErrorResponse(w, req, dockerdriver.ErrorResponse{Err: err.Error()})
 			return
 		}
 
 		removeResponse := client.Remove(EnvWithMonitor(logger, req.Context(), w), removeRequest)
 		if removeResponse.Err != "" {
 			logger.Error("failed-remove-volume", errors.New(removeResponse.Err))
-			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, removeResponse)
+			writeErrorResponse(w, req, removeResponse)
 			return
 		}
 
@@ -2,6 +2,7 @@ package driverhttp_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -18,24 +19,28 @@ import (
 	. "github.com/onsi/gomega"
 )
 
-type RecordingCloseNotifier struct {
+type CancellableRecorder struct {
 	*httptest.ResponseRecorder
-	cn chan bool
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
-func (rcn *RecordingCloseNotifier) CloseNotify() <-chan bool {
-	return rcn.cn
+func NewCancellableRecorder() *CancellableRecorder {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &CancellableRecorder{ResponseRecorder: httptest.NewRecorder(), ctx: ctx, cancel: cancel}
 }
 
-func (rcn *RecordingCloseNotifier) SimulateClientCancel() {
-	rcn.cn <- true
+// SimulateClientCancel cancels the request context, as the server does when
+// the client closes the connection.
+func (cr *CancellableRecorder) SimulateClientCancel() {
+	cr.cancel()
 }
 
 var _ = Describe("Docker Driver Handlers", func() {
 
 	var testLogger = lagertest.NewTestLogger("HandlersTest")
 
-	var ErrorResponse = func(res *RecordingCloseNotifier) dockerdriver.ErrorResponse {
+	var ErrorResponse = func(res *CancellableRecorder) dockerdriver.ErrorResponse {
 		response := dockerdriver.ErrorResponse{}
 
 		body, err := io.ReadAll(res.Body)
@@ -51,7 +56,7 @@ var _ = Describe("Docker Driver Handlers", func() {
 		var (
 			err    error
 			req    *http.Request
-			res    *RecordingCloseNotifier
+			res    *CancellableRecorder
 			driver *dockerdriverfakes.FakeDriver
 			wg     sync.WaitGroup
 
@@ -64,16 +69,13 @@ var _ = Describe("Docker Driver Handlers", func() {
 			subject, err = driverhttp.NewHandler(testLogger, driver)
 			Expect(err).NotTo(HaveOccurred())
 
-			res = &RecordingCloseNotifier{
-				ResponseRecorder: httptest.NewRecorder(),
-				cn:               make(chan bool, 1),
-			}
+			res = NewCancellableRecorder()
 
 			route, found := dockerdriver.Routes.FindRouteByName(dockerdriver.ActivateRoute)
 			Expect(found).To(BeTrue())
 
 			path := fmt.Sprintf("http://0.0.0.0%s", route.Path)
-			req, err = http.NewRequest("POST", path, bytes.NewReader([]byte{}))
+			req, err = http.NewRequestWithContext(res.ctx, "POST", path, bytes.NewReader([]byte{}))
 			Expect(err).NotTo(HaveOccurred())
 		})
 
@@ -153,7 +155,7 @@ var _ = Describe("Docker Driver Handlers", func() {
 		var (
 			err    error
 			req    *http.Request
-			res    *RecordingCloseNotifier
+			res    *CancellableRecorder
 			driver *dockerdriverfakes.FakeDriver
 			wg     sync.WaitGroup
 
@@ -166,16 +168,13 @@ var _ = Describe("Docker Driver Handlers", func() {
 			subject, err = driverhttp.NewHandler(testLogger, driver)
 			Expect(err).NotTo(HaveOccurred())
 
-			res = &RecordingCloseNotifier{
-				ResponseRecorder: httptest.NewRecorder(),
-				cn:               make(chan bool, 1),
-			}
+			res = NewCancellableRecorder()
 
 			route, found := dockerdriver.Routes.FindRouteByName(dockerdriver.ListRoute)
 			Expect(found).To(BeTrue())
 
 			path := fmt.Sprintf("http://0.0.0.0%s", route.Path)
-			req, err = http.NewRequest("POST", path, bytes.NewReader([]byte{}))
+			req, err = http.NewRequestWithContext(res.ctx, "POST", path, bytes.NewReader([]byte{}))
 			Expect(err).NotTo(HaveOccurred())
 
 		})
@@ -186,6 +185,7 @@ var _ = Describe("Docker Driver Handlers", func() {
 					Name:       "fake-volume",
 					Mountpoint: "fake-mountpoint",
 				}
+				volume.SetHealth(dockerdriver.VolumeHealthy)
 				listResponse := dockerdriver.ListResponse{
 					Volumes: []dockerdriver.VolumeInfo{volume},
 					Err:     "",
@@ -216,6 +216,7 @@ var _ = Describe("Docker Driver Handlers", func() {
 
 				Expect(listResponse.Err).Should(BeEmpty())
 				Expect(listResponse.Volumes[0].Name).Should(Equal("fake-volume"))
+				Expect(listResponse.Volumes[0].Health()).Should(Equal(dockerdriver.VolumeHealthy))
 			})
 		})
 
@@ -264,7 +265,7 @@ var _ = Describe("Docker Driver Handlers", func() {
 		var (
 			err    error
 			req    *http.Request
-			res    *RecordingCloseNotifier
+			res    *CancellableRecorder
 			driver *dockerdriverfakes.FakeDriver
 			wg     sync.WaitGroup
 
@@ -289,10 +290,7 @@ var _ = Describe("Docker Driver Handlers", func() {
 			subject, err = driverhttp.NewHandler(testLogger, driver)
 			Expect(err).NotTo(HaveOccurred())
 
-			res = &RecordingCloseNotifier{
-				ResponseRecorder: httptest.NewRecorder(),
-				cn:               make(chan bool, 1),
-			}
+			res = NewCancellableRecorder()
 
 			route, found := dockerdriver.Routes.FindRouteByName(dockerdriver.MountRoute)
 			Expect(found).To(BeTrue())
@@ -301,11 +299,12 @@ var _ = Describe("Docker Driver Handlers", func() {
 
 			MountRequest := dockerdriver.MountRequest{
 				Name: "some-volume",
+				ID:   "some-container-id",
 			}
 			mountJSONRequest, err := json.Marshal(MountRequest)
 			Expect(err).NotTo(HaveOccurred())
 
-			req, err = http.NewRequest("POST", path, bytes.NewReader(mountJSONRequest))
+			req, err = http.NewRequestWithContext(res.ctx, "POST", path, bytes.NewReader(mountJSONRequest))
 			Expect(err).NotTo(HaveOccurred())
 		})
 
@@ -330,6 +329,14 @@ var _ = Describe("Docker Driver Handlers", func() {
 
 				ExpectMountPointToEqual("dummy_path")
 			})
+
+			It("should pass the mount ID through to the driver", func() {
+				wg.Wait()
+
+				Expect(driver.MountCallCount()).To(Equal(1))
+				_, mountRequest := driver.MountArgsForCall(0)
+				Expect(mountRequest).To(Equal(dockerdriver.MountRequest{Name: "some-volume", ID: "some-container-id"}))
+			})
 		})
 
 		Context("when the mount hangs and the client closes the connection", func() {
@@ -370,7 +377,7 @@ var _ = Describe("Docker Driver Handlers", func() {
 		var (
 			err    error
 			req    *http.Request
-			res    *RecordingCloseNotifier
+			res    *CancellableRecorder
 			driver *dockerdriverfakes.FakeDriver
 			wg     sync.WaitGroup
 
@@ -383,20 +390,17 @@ var _ = Describe("Docker Driver Handlers", func() {
 			subject, err = driverhttp.NewHandler(testLogger, driver)
 			Expect(err).NotTo(HaveOccurred())
 
-			unmountRequest := dockerdriver.UnmountRequest{}
+			unmountRequest := dockerdriver.UnmountRequest{Name: "some-volume", ID: "some-container-id"}
 			unmountJSONRequest, err := json.Marshal(unmountRequest)
 			Expect(err).NotTo(HaveOccurred())
 
-			res = &RecordingCloseNotifier{
-				ResponseRecorder: httptest.NewRecorder(),
-				cn:               make(chan bool, 1),
-			}
+			res = NewCancellableRecorder()
 
 			route, found := dockerdriver.Routes.FindRouteByName(dockerdriver.UnmountRoute)
 			Expect(found).To(BeTrue())
 
 			path := fmt.Sprintf("http://0.0.0.0%s", route.Path)
-			req, err = http.NewRequest("POST", path, bytes.NewReader(unmountJSONRequest))
+			req, err = http.NewRequestWithContext(res.ctx, "POST", path, bytes.NewReader(unmountJSONRequest))
 			Expect(err).NotTo(HaveOccurred())
 		})
 
@@ -417,6 +421,14 @@ var _ = Describe("Docker Driver Handlers", func() {
 				wg.Wait()
 				Expect(res.Code).To(Equal(200))
 			})
+
+			It("should pass the mount ID through to the driver", func() {
+				wg.Wait()
+
+				Expect(driver.UnmountCallCount()).To(Equal(1))
+				_, unmountRequest := driver.UnmountArgsForCall(0)
+				Expect(unmountRequest).To(Equal(dockerdriver.UnmountRequest{Name: "some-volume", ID: "some-container-id"}))
+			})
 		})
 
 		Context("when the unmount hangs and the client closes the connection", func() {
@@ -457,7 +469,7 @@ var _ = Describe("Docker Driver Handlers", func() {
 		var (
 			err    error
 			req    *http.Request
-			res    *RecordingCloseNotifier
+			res    *CancellableRecorder
 			driver *dockerdriverfakes.FakeDriver
 			wg     sync.WaitGroup
 
@@ -470,10 +482,7 @@ var _ = Describe("Docker Driver Handlers", func() {
 			subject, err = driverhttp.NewHandler(testLogger, driver)
 			Expect(err).NotTo(HaveOccurred())
 
-			res = &RecordingCloseNotifier{
-				ResponseRecorder: httptest.NewRecorder(),
-				cn:               make(chan bool, 1),
-			}
+			res = NewCancellableRecorder()
 
 			getRequest := dockerdriver.GetRequest{}
 			getJSONRequest, err := json.Marshal(getRequest)
@@ -484,13 +493,15 @@ var _ = Describe("Docker Driver Handlers", func() {
 			Expect(found).To(BeTrue())
 
 			path := fmt.Sprintf("http://0.0.0.0%s", route.Path)
-			req, err = http.NewRequest("POST", path, bytes.NewReader(getJSONRequest))
+			req, err = http.NewRequestWithContext(res.ctx, "POST", path, bytes.NewReader(getJSONRequest))
 			Expect(err).NotTo(HaveOccurred())
 		})
 
 		Context("when get is successful", func() {
 			JustBeforeEach(func() {
-				driver.GetReturns(dockerdriver.GetResponse{Volume: dockerdriver.VolumeInfo{Name: "some-volume", Mountpoint: "dummy_path"}})
+				volume := dockerdriver.VolumeInfo{Name: "some-volume", Mountpoint: "dummy_path"}
+				volume.SetSource("some-server:/share")
+				driver.GetReturns(dockerdriver.GetResponse{Volume: volume})
 
 				wg.Add(1)
 
@@ -514,6 +525,7 @@ var _ = Describe("Docker Driver Handlers", func() {
 
 				Expect(getResponse.Volume.Name).Should(Equal("some-volume"))
 				Expect(getResponse.Volume.Mountpoint).Should(Equal("dummy_path"))
+				Expect(getResponse.Volume.Source()).Should(Equal("some-server:/share"))
 			})
 		})
 
@@ -555,7 +567,7 @@ var _ = Describe("Docker Driver Handlers", func() {
 		var (
 			err    error
 			req    *http.Request
-			res    *RecordingCloseNotifier
+			res    *CancellableRecorder
 			driver *dockerdriverfakes.FakeDriver
 			wg     sync.WaitGroup
 
@@ -568,10 +580,7 @@ var _ = Describe("Docker Driver Handlers", func() {
 			subject, err = driverhttp.NewHandler(testLogger, driver)
 			Expect(err).NotTo(HaveOccurred())
 
-			res = &RecordingCloseNotifier{
-				ResponseRecorder: httptest.NewRecorder(),
-				cn:               make(chan bool, 1),
-			}
+			res = NewCancellableRecorder()
 
 			pathRequest := dockerdriver.PathRequest{Name: "some-volume"}
 			pathJSONRequest, err := json.Marshal(pathRequest)
@@ -581,7 +590,7 @@ var _ = Describe("Docker Driver Handlers", func() {
 			Expect(found).To(BeTrue())
 
 			path := fmt.Sprintf("http://0.0.0.0%s", route.Path)
-			req, err = http.NewRequest("POST", path, bytes.NewReader(pathJSONRequest))
+			req, err = http.NewRequestWithContext(res.ctx, "POST", path, bytes.NewReader(pathJSONRequest))
 			Expect(err).NotTo(HaveOccurred())
 		})
 
@@ -653,7 +662,7 @@ var _ = Describe("Docker Driver Handlers", func() {
 		var (
 			err    error
 			req    *http.Request
-			res    *RecordingCloseNotifier
+			res    *CancellableRecorder
 			driver *dockerdriverfakes.FakeDriver
 			wg     sync.WaitGroup
 
@@ -666,10 +675,7 @@ var _ = Describe("Docker Driver Handlers", func() {
 			subject, err = driverhttp.NewHandler(testLogger, driver)
 			Expect(err).NotTo(HaveOccurred())
 
-			res = &RecordingCloseNotifier{
-				ResponseRecorder: httptest.NewRecorder(),
-				cn:               make(chan bool, 1),
-			}
+			res = NewCancellableRecorder()
 
 			createRequest := dockerdriver.CreateRequest{Name: "some-volume"}
 			createJSONRequest, err := json.Marshal(createRequest)
@@ -679,7 +685,7 @@ var _ = Describe("Docker Driver Handlers", func() {
 			Expect(found).To(BeTrue())
 
 			path := fmt.Sprintf("http://0.0.0.0%s", route.Path)
-			req, err = http.NewRequest("POST", path, bytes.NewReader(createJSONRequest))
+			req, err = http.NewRequestWithContext(res.ctx, "POST", path, bytes.NewReader(createJSONRequest))
 			Expect(err).NotTo(HaveOccurred())
 		})
 
@@ -744,7 +750,7 @@ var _ = Describe("Docker Driver Handlers", func() {
 		var (
 			err    error
 			req    *http.Request
-			res    *RecordingCloseNotifier
+			res    *CancellableRecorder
 			driver *dockerdriverfakes.FakeDriver
 			wg     sync.WaitGroup
 
@@ -757,10 +763,7 @@ var _ = Describe("Docker Driver Handlers", func() {
 			subject, err = driverhttp.NewHandler(testLogger, driver)
 			Expect(err).NotTo(HaveOccurred())
 
-			res = &RecordingCloseNotifier{
-				ResponseRecorder: httptest.NewRecorder(),
-				cn:               make(chan bool, 1),
-			}
+			res = NewCancellableRecorder()
 
 			removeRequest := dockerdriver.RemoveRequest{Name: "some-volume"}
 			removeJSONRequest, err := json.Marshal(removeRequest)
@@ -770,7 +773,7 @@ var _ = Describe("Docker Driver Handlers", func() {
 			Expect(found).To(BeTrue())
 
 			path := fmt.Sprintf("http://0.0.0.0%s", route.Path)
-			req, err = http.NewRequest("POST", path, bytes.NewReader(removeJSONRequest))
+			req, err = http.NewRequestWithContext(res.ctx, "POST", path, bytes.NewReader(removeJSONRequest))
 			Expect(err).NotTo(HaveOccurred())
 		})
 
@@ -835,7 +838,7 @@ var _ = Describe("Docker Driver Handlers", func() {
 		var (
 			err    error
 			req    *http.Request
-			res    *RecordingCloseNotifier
+			res    *CancellableRecorder
 			driver *dockerdriverfakes.FakeDriver
 			wg     sync.WaitGroup
 
@@ -848,16 +851,13 @@ var _ = Describe("Docker Driver Handlers", func() {
 			subject, err = driverhttp.NewHandler(testLogger, driver)
 			Expect(err).NotTo(HaveOccurred())
 
-			res = &RecordingCloseNotifier{
-				ResponseRecorder: httptest.NewRecorder(),
-				cn:               make(chan bool, 1),
-			}
+			res = NewCancellableRecorder()
 
 			route, found := dockerdriver.Routes.FindRouteByName(dockerdriver.CapabilitiesRoute)
 			Expect(found).To(BeTrue())
 
 			path := fmt.Sprintf("http://0.0.0.0%s", route.Path)
-			req, err = http.NewRequest("POST", path, bytes.NewReader([]byte{}))
+			req, err = http.NewRequestWithContext(res.ctx, "POST", path, bytes.NewReader([]byte{}))
 			Expect(err).NotTo(HaveOccurred())
 		})
 
@@ -889,4 +889,75 @@ var _ = Describe("Docker Driver Handlers", func() {
 			})
 		})
 	})
+
+	Context("when unsafe errors are redacted", func() {
+		var (
+			err        error
+			req        *http.Request
+			res        *CancellableRecorder
+			driver     *dockerdriverfakes.FakeDriver
+			testLogger *lagertest.TestLogger
+
+			subject http.Handler
+		)
+
+		BeforeEach(func() {
+			driver = &dockerdriverfakes.FakeDriver{}
+			testLogger = lagertest.NewTestLogger("HandlersTest")
+
+			subject, err = driverhttp.NewHandler(testLogger, driver, driverhttp.WithUnsafeErrorRedaction())
+			Expect(err).NotTo(HaveOccurred())
+
+			mountJSONRequest, err := json.Marshal(dockerdriver.MountRequest{Name: "some-volume"})
+			Expect(err).NotTo(HaveOccurred())
+
+			res = NewCancellableRecorder()
+
+			route, found := dockerdriver.Routes.FindRouteByName(dockerdriver.MountRoute)
+			Expect(found).To(BeTrue())
+
+			req, err = http.NewRequestWithContext(res.ctx, "POST", fmt.Sprintf("http://0.0.0.0%s", route.Path), bytes.NewReader(mountJSONRequest))
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when the driver returns an unsafe error", func() {
+			BeforeEach(func() {
+				driver.MountReturns(dockerdriver.MountResponse{Err: "mount.nfs: access denied by server while mounting 10.0.0.1:/export for user alice"})
+			})
+
+			It("should replace it with a generic SafeError and log the original", func() {
+				subject.ServeHTTP(res, req)
+
+				response := ErrorResponse(res)
+				Expect(response.Err).NotTo(ContainSubstring("10.0.0.1"))
+				Expect(response.ErrCode).To(Equal(dockerdriver.ErrCodePermissionDenied))
+
+				safeError, ok := dockerdriver.ParseSafeError(response.Err)
+				Expect(ok).To(BeTrue())
+				Expect(safeError.SafeDescription).To(MatchRegexp("correlation id [0-9a-f-]{36}$"))
+
+				correlationID := safeError.SafeDescription[len(safeError.SafeDescription)-36:]
+				Expect(testLogger.Logs()).To(ContainElement(SatisfyAll(
+					HaveField("Message", ContainSubstring("redacted-unsafe-error")),
+					HaveField("Data", HaveKeyWithValue("correlation-id", correlationID)),
+					HaveField("Data", HaveKeyWithValue("error", ContainSubstring("10.0.0.1"))),
+				)))
+			})
+		})
+
+		Context("when the driver returns a SafeError", func() {
+			BeforeEach(func() {
+				driver.MountReturns(dockerdriver.MountResponse{Err: dockerdriver.SafeErrorMessage(dockerdriver.SafeError{SafeDescription: "share is not reachable"})})
+			})
+
+			It("should pass it through", func() {
+				subject.ServeHTTP(res, req)
+
+				response := ErrorResponse(res)
+				safeError, ok := dockerdriver.ParseSafeError(response.Err)
+				Expect(ok).To(BeTrue())
+				Expect(safeError.SafeDescription).To(Equal("share is not reachable"))
+			})
+		})
+	})
 })
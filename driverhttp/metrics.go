@@ -0,0 +1,148 @@
+package driverhttp
+
+import (
+	"fmt"
+	"time"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/drivermetrics"
+)
+
+// requestMetrics are the metrics kept for each route by both the handlers
+// (subsystem "handler") and remoteClient (subsystem "client").
+type requestMetrics struct {
+	driver   string
+	requests *drivermetrics.CounterVec
+	errors   *drivermetrics.CounterVec
+	duration *drivermetrics.HistogramVec
+	inFlight *drivermetrics.GaugeVec
+}
+
+func newRequestMetrics(registry *drivermetrics.Registry, subsystem string, driverName string) *requestMetrics {
+	prefix := "dockerdriver_" + subsystem + "_"
+	return &requestMetrics{
+		driver:   driverName,
+		requests: registry.Counter(prefix+"requests_total", "Requests by route.", "driver", "route"),
+		errors:   registry.Counter(prefix+"errors_total", "Failed requests by route and error class.", "driver", "route", "class"),
+		duration: registry.Histogram(prefix+"request_duration_seconds", "Request latency by route.", nil, "driver", "route"),
+		inFlight: registry.Gauge(prefix+"requests_in_flight", "Requests in progress by route.", "driver", "route"),
+	}
+}
+
+// start records a request to route; call the returned func with the outcome
+// when it is done.
+func (m *requestMetrics) start(route string, now func() time.Time) func(err string, code dockerdriver.ErrorCode) {
+	startedAt := now()
+	inFlight := m.inFlight.WithLabelValues(m.driver, route)
+	inFlight.Inc()
+
+	return func(err string, code dockerdriver.ErrorCode) {
+		inFlight.Dec()
+		m.requests.WithLabelValues(m.driver, route).Inc()
+		m.duration.WithLabelValues(m.driver, route).Observe(now().Sub(startedAt).Seconds())
+		if err != "" {
+			m.errors.WithLabelValues(m.driver, route, errorClass(err, code)).Inc()
+		}
+	}
+}
+
+// errorClass keeps the class label to the handful of error codes, so a
+// driver's error messages can't blow up the number of series.
+func errorClass(err string, code dockerdriver.ErrorCode) string {
+	if code == "" {
+		code = dockerdriver.ClassifyError(err)
+	}
+	if code == "" {
+		return "unknown"
+	}
+	return string(code)
+}
+
+// WithClientMetrics records the client's requests to the driver in registry,
+// labelled with driverName.
+func WithClientMetrics(registry *drivermetrics.Registry, driverName string) RemoteClientOption {
+	return func(r *remoteClient) {
+		r.metrics = newRequestMetrics(registry, "client", driverName)
+	}
+}
+
+// WithHandlerMetrics records the requests the handler serves in registry,
+// labelled with driverName.
+func WithHandlerMetrics(registry *drivermetrics.Registry, driverName string) HandlerOption {
+	return func(c *handlerConfig) {
+		c.metrics = newRequestMetrics(registry, "handler", driverName)
+	}
+}
+
+// finish records a request's outcome with done. A panic is counted as an error
+// and passed on, for the handler to recover.
+func finish(done func(err string, code dockerdriver.ErrorCode), recovered interface{}, err string, code dockerdriver.ErrorCode) {
+	if recovered != nil {
+		done(fmt.Sprintf("panic: %v", recovered), "")
+		panic(recovered)
+	}
+	done(err, code)
+}
+
+type instrumentedDriver struct {
+	dockerdriver.Driver
+	metrics *requestMetrics
+}
+
+func newInstrumentedDriver(driver dockerdriver.Driver, metrics *requestMetrics) dockerdriver.Driver {
+	return &instrumentedDriver{Driver: driver, metrics: metrics}
+}
+
+func (d *instrumentedDriver) Activate(env dockerdriver.Env) (response dockerdriver.ActivateResponse) {
+	done := d.metrics.start(dockerdriver.ActivateRoute, time.Now)
+	defer func() { finish(done, recover(), response.Err, response.ErrCode) }()
+	return d.Driver.Activate(env)
+}
+
+func (d *instrumentedDriver) Get(env dockerdriver.Env, getRequest dockerdriver.GetRequest) (response dockerdriver.GetResponse) {
+	done := d.metrics.start(dockerdriver.GetRoute, time.Now)
+	defer func() { finish(done, recover(), response.Err, response.ErrCode) }()
+	return d.Driver.Get(env, getRequest)
+}
+
+func (d *instrumentedDriver) List(env dockerdriver.Env) (response dockerdriver.ListResponse) {
+	done := d.metrics.start(dockerdriver.ListRoute, time.Now)
+	defer func() { finish(done, recover(), response.Err, response.ErrCode) }()
+	return d.Driver.List(env)
+}
+
+func (d *instrumentedDriver) Mount(env dockerdriver.Env, mountRequest dockerdriver.MountRequest) (response dockerdriver.MountResponse) {
+	done := d.metrics.start(dockerdriver.MountRoute, time.Now)
+	defer func() { finish(done, recover(), response.Err, response.ErrCode) }()
+	return d.Driver.Mount(env, mountRequest)
+}
+
+func (d *instrumentedDriver) Path(env dockerdriver.Env, pathRequest dockerdriver.PathRequest) (response dockerdriver.PathResponse) {
+	done := d.metrics.start(dockerdriver.PathRoute, time.Now)
+	defer func() { finish(done, recover(), response.Err, response.ErrCode) }()
+	return d.Driver.Path(env, pathRequest)
+}
+
+func (d *instrumentedDriver) Unmount(env dockerdriver.Env, unmountRequest dockerdriver.UnmountRequest) (response dockerdriver.ErrorResponse) {
+	done := d.metrics.start(dockerdriver.UnmountRoute, time.Now)
+	defer func() { finish(done, recover(), response.Err, response.ErrCode) }()
+	return d.Driver.Unmount(env, unmountRequest)
+}
+
+func (d *instrumentedDriver) Capabilities(env dockerdriver.Env) (response dockerdriver.CapabilitiesResponse) {
+	done := d.metrics.start(dockerdriver.CapabilitiesRoute, time.Now)
+	defer func() { finish(done, recover(), "", "") }()
+	return d.Driver.Capabilities(env)
+}
+
+func (d *instrumentedDriver) Create(env dockerdriver.Env, createRequest dockerdriver.CreateRequest) (response dockerdriver.ErrorResponse) {
+	done := d.metrics.start(dockerdriver.CreateRoute, time.Now)
+	defer func() { finish(done, recover(), response.Err, response.ErrCode) }()
+	return d.Driver.Create(env, createRequest)
+}
+
+func (d *instrumentedDriver) Remove(env dockerdriver.Env, removeRequest dockerdriver.RemoveRequest) (response dockerdriver.ErrorResponse) {
+	done := d.metrics.start(dockerdriver.RemoveRoute, time.Now)
+	defer func() { finish(done, recover(), response.Err, response.ErrCode) }()
+	return d.Driver.Remove(env, removeRequest)
+}
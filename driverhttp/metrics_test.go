@@ -0,0 +1,117 @@
+package driverhttp_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/dockerdriverfakes"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/dockerdriver/drivermetrics"
+	"code.cloudfoundry.org/goshims/http_wrap/http_fake"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Metrics", func() {
+	var (
+		testLogger *lagertest.TestLogger
+		env        dockerdriver.Env
+		registry   *drivermetrics.Registry
+	)
+
+	BeforeEach(func() {
+		testLogger = lagertest.NewTestLogger("metrics-test")
+		env = driverhttp.NewHttpDriverEnv(testLogger, context.TODO())
+		registry = drivermetrics.NewRegistry()
+	})
+
+	counter := func(name string, labelValues ...string) float64 {
+		labelNames := []string{"driver", "route"}
+		if len(labelValues) == 3 {
+			labelNames = append(labelNames, "class")
+		}
+		return registry.Counter(name, "", labelNames...).WithLabelValues(labelValues...).Value()
+	}
+
+	Context("when the remote client has metrics", func() {
+		var (
+			httpClient *http_fake.FakeClient
+			driver     dockerdriver.Driver
+		)
+
+		BeforeEach(func() {
+			httpClient = new(http_fake.FakeClient)
+			driver = driverhttp.NewRemoteClientWithClient("http://127.0.0.1:8080", nil, httpClient, fakeclock.NewFakeClock(time.Now()), driverhttp.WithClientMetrics(registry, "some-driver"))
+		})
+
+		It("should count requests and errors by route and class", func() {
+			httpClient.DoReturnsOnCall(0, &http.Response{StatusCode: http.StatusOK, Body: stringCloser{bytes.NewBufferString(`{"Mountpoint":"somePath"}`)}}, nil)
+			httpClient.DoReturnsOnCall(1, nil, fmt.Errorf("connection refused"))
+
+			driver.Mount(env, dockerdriver.MountRequest{Name: "fake-volume"})
+			driver.Mount(env, dockerdriver.MountRequest{Name: "fake-volume"})
+
+			Expect(counter("dockerdriver_client_requests_total", "some-driver", "mount")).To(Equal(2.0))
+			Expect(counter("dockerdriver_client_errors_total", "some-driver", "mount", "unavailable")).To(Equal(1.0))
+			Expect(registry.Histogram("dockerdriver_client_request_duration_seconds", "", nil, "driver", "route").WithLabelValues("some-driver", "mount").Count()).To(Equal(uint64(2)))
+			Expect(registry.Gauge("dockerdriver_client_requests_in_flight", "", "driver", "route").WithLabelValues("some-driver", "mount").Value()).To(Equal(0.0))
+		})
+	})
+
+	Context("when the handler has metrics", func() {
+		var (
+			fakeDriver *dockerdriverfakes.FakeDriver
+			handler    http.Handler
+			inFlight   float64
+		)
+
+		BeforeEach(func() {
+			fakeDriver = &dockerdriverfakes.FakeDriver{}
+			fakeDriver.MountStub = func(dockerdriver.Env, dockerdriver.MountRequest) dockerdriver.MountResponse {
+				inFlight = registry.Gauge("dockerdriver_handler_requests_in_flight", "", "driver", "route").WithLabelValues("some-driver", "mount").Value()
+				return dockerdriver.MountResponse{Err: "Volume fake-volume does not exist"}
+			}
+
+			var err error
+			handler, err = driverhttp.NewHandler(testLogger, fakeDriver, driverhttp.WithHandlerMetrics(registry, "some-driver"))
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should record the request while it runs and its outcome after", func() {
+			body, err := json.Marshal(dockerdriver.MountRequest{Name: "fake-volume"})
+			Expect(err).NotTo(HaveOccurred())
+
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/VolumeDriver.Mount", bytes.NewReader(body)))
+
+			Expect(inFlight).To(Equal(1.0))
+			Expect(counter("dockerdriver_handler_requests_total", "some-driver", "mount")).To(Equal(1.0))
+			Expect(counter("dockerdriver_handler_errors_total", "some-driver", "mount", "not-found")).To(Equal(1.0))
+
+			var text bytes.Buffer
+			Expect(registry.WriteText(&text)).To(Succeed())
+			Expect(text.String()).To(ContainSubstring(`dockerdriver_handler_request_duration_seconds_count{driver="some-driver",route="mount"} 1`))
+		})
+
+		It("should count a driver panic as a failed request", func() {
+			fakeDriver.MountStub = func(dockerdriver.Env, dockerdriver.MountRequest) dockerdriver.MountResponse {
+				panic("something terrible")
+			}
+			body, err := json.Marshal(dockerdriver.MountRequest{Name: "fake-volume"})
+			Expect(err).NotTo(HaveOccurred())
+
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/VolumeDriver.Mount", bytes.NewReader(body)))
+
+			Expect(registry.Gauge("dockerdriver_handler_requests_in_flight", "", "driver", "route").WithLabelValues("some-driver", "mount").Value()).To(Equal(0.0))
+			Expect(counter("dockerdriver_handler_requests_total", "some-driver", "mount")).To(Equal(1.0))
+			Expect(counter("dockerdriver_handler_errors_total", "some-driver", "mount", "unknown")).To(Equal(1.0))
+		})
+	})
+})
@@ -0,0 +1,99 @@
+package driverhttp
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/google/uuid"
+)
+
+// DefaultMaxRequestBodyBytes is far more than any docker volume request
+// needs; it is there to stop a client making the driver buffer an endless
+// body.
+const DefaultMaxRequestBodyBytes = 1024 * 1024
+
+// Middleware wraps the handler for one route, named by its dockerdriver route
+// constant, e.g. dockerdriver.MountRoute.
+type Middleware func(route string, next http.Handler) http.Handler
+
+// WithMiddleware appends to the handler's middleware chain. The first
+// middleware added is the outermost, so it sees each request first.
+func WithMiddleware(middleware ...Middleware) HandlerOption {
+	return func(c *handlerConfig) {
+		c.middleware = append(c.middleware, middleware...)
+	}
+}
+
+// WithMaxRequestBodyBytes overrides DefaultMaxRequestBodyBytes; zero or less
+// removes the limit.
+func WithMaxRequestBodyBytes(maxBytes int64) HandlerOption {
+	return func(c *handlerConfig) {
+		c.maxRequestBodyBytes = maxBytes
+	}
+}
+
+// chain wraps handler in, from the outside in, request identification, panic
+// recovery, the body size limit, trace extraction and the configured
+// middleware.
+func (c handlerConfig) chain(logger lager.Logger, route string, handler http.Handler) http.Handler {
+	for i := len(c.middleware) - 1; i >= 0; i-- {
+		handler = c.middleware[i](route, handler)
+	}
+	handler = traceRequests(c.tracer, route, handler)
+	if c.maxRequestBodyBytes > 0 {
+		handler = limitRequestBody(c.maxRequestBodyBytes, handler)
+	}
+	return identifyRequests(recoverPanics(logger, route, c.redactUnsafeErrors, handler))
+}
+
+// recoverPanics answers with an Err the way any other driver failure is
+// answered, since docker expects an error body rather than a dropped
+// connection. The panic never reaches a redactingDriver, so it is redacted
+// here when redactErrors is set.
+func recoverPanics(logger lager.Logger, route string, redactErrors bool, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+
+			err := fmt.Errorf("panic handling %s: %v", route, recovered)
+			data := lager.Data{"route": route, "stack": string(debug.Stack())}
+			message := err.Error()
+			if redactErrors {
+				correlationID := uuid.NewString()
+				data["correlation-id"] = correlationID
+				message = redactedErrorMessage(correlationID)
+			}
+
+			logger.Session("recover-panic", requestLogData(req)).Error("driver-panicked", err, data)
+			writeErrorResponse(w, req, dockerdriver.ErrorResponse{Err: message})
+		}()
+
+		next.ServeHTTP(w, req)
+	})
+}
+
+// limitRequestBody turns away requests that declare too large a body up
+// front, and caps the rest so that reading past the limit fails.
+func limitRequestBody(maxBytes int64, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.ContentLength > maxBytes {
+			writeErrorResponse(w, req, dockerdriver.ErrorResponse{
+				Err:     fmt.Sprintf("request body of %d bytes exceeds the limit of %d bytes", req.ContentLength, maxBytes),
+				ErrCode: dockerdriver.ErrCodeInvalidOptions,
+			})
+			return
+		}
+
+		req.Body = http.MaxBytesReader(w, req.Body, maxBytes)
+		next.ServeHTTP(w, req)
+	})
+}
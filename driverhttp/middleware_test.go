@@ -0,0 +1,142 @@
+package driverhttp_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/dockerdriverfakes"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("Handler middleware", func() {
+	var (
+		testLogger *lagertest.TestLogger
+		driver     *dockerdriverfakes.FakeDriver
+		opts       []driverhttp.HandlerOption
+		res        *httptest.ResponseRecorder
+	)
+
+	serve := func(route string, body io.Reader) dockerdriver.ErrorResponse {
+		handler, err := driverhttp.NewHandler(testLogger, driver, opts...)
+		Expect(err).NotTo(HaveOccurred())
+
+		path, found := dockerdriver.Routes.FindRouteByName(route)
+		Expect(found).To(BeTrue())
+
+		req := httptest.NewRequest("POST", path.Path, body)
+		res = httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+
+		var response dockerdriver.ErrorResponse
+		Expect(json.Unmarshal(res.Body.Bytes(), &response)).To(Succeed())
+		return response
+	}
+
+	mountRequest := func() io.Reader {
+		body, err := json.Marshal(dockerdriver.MountRequest{Name: "some-volume"})
+		Expect(err).NotTo(HaveOccurred())
+		return bytes.NewReader(body)
+	}
+
+	BeforeEach(func() {
+		testLogger = lagertest.NewTestLogger("middleware-test")
+		driver = &dockerdriverfakes.FakeDriver{}
+		opts = nil
+	})
+
+	Context("when middleware is configured", func() {
+		var calls []string
+
+		record := func(name string) driverhttp.Middleware {
+			return func(route string, next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					calls = append(calls, name+":"+route)
+					next.ServeHTTP(w, req)
+				})
+			}
+		}
+
+		BeforeEach(func() {
+			calls = nil
+			opts = append(opts,
+				driverhttp.WithMiddleware(record("outer")),
+				driverhttp.WithMiddleware(record("inner")),
+			)
+			driver.MountStub = func(dockerdriver.Env, dockerdriver.MountRequest) dockerdriver.MountResponse {
+				calls = append(calls, "driver")
+				return dockerdriver.MountResponse{Mountpoint: "/some/path"}
+			}
+		})
+
+		It("should run it in order with the route name", func() {
+			serve(dockerdriver.MountRoute, mountRequest())
+
+			Expect(calls).To(Equal([]string{"outer:mount", "inner:mount", "driver"}))
+		})
+	})
+
+	Context("when the driver panics", func() {
+		BeforeEach(func() {
+			driver.MountStub = func(dockerdriver.Env, dockerdriver.MountRequest) dockerdriver.MountResponse {
+				panic("something terrible")
+			}
+		})
+
+		It("should respond with an Err and log the panic", func() {
+			response := serve(dockerdriver.MountRoute, mountRequest())
+
+			Expect(res.Code).To(Equal(driverhttp.StatusInternalServerError))
+			Expect(response.Err).To(Equal("panic handling mount: something terrible"))
+			Expect(testLogger.Buffer()).To(gbytes.Say("driver-panicked"))
+		})
+
+		Context("when unsafe errors are redacted", func() {
+			BeforeEach(func() {
+				opts = append(opts, driverhttp.WithUnsafeErrorRedaction())
+			})
+
+			It("should only log the panic and respond with a generic SafeError", func() {
+				response := serve(dockerdriver.MountRoute, mountRequest())
+
+				Expect(response.Err).NotTo(ContainSubstring("something terrible"))
+				safeError, ok := dockerdriver.ParseSafeError(response.Err)
+				Expect(ok).To(BeTrue())
+
+				panicLog := testLogger.Logs()[len(testLogger.Logs())-1]
+				Expect(panicLog.Message).To(HaveSuffix("driver-panicked"))
+				Expect(panicLog.Data).To(HaveKeyWithValue("error", ContainSubstring("something terrible")))
+				Expect(safeError.SafeDescription).To(ContainSubstring(panicLog.Data["correlation-id"].(string)))
+			})
+		})
+	})
+
+	Context("when the request body is too large", func() {
+		BeforeEach(func() {
+			opts = append(opts, driverhttp.WithMaxRequestBodyBytes(16))
+		})
+
+		It("should turn away a request that declares its length", func() {
+			response := serve(dockerdriver.MountRoute, mountRequest())
+
+			Expect(response.Err).To(ContainSubstring("exceeds the limit of 16 bytes"))
+			Expect(response.ErrCode).To(Equal(dockerdriver.ErrCodeInvalidOptions))
+			Expect(driver.MountCallCount()).To(Equal(0))
+		})
+
+		It("should stop reading a request that does not declare its length", func() {
+			response := serve(dockerdriver.MountRoute, io.MultiReader(strings.NewReader(`{"Name":"`), strings.NewReader(strings.Repeat("x", 64)+`"}`)))
+
+			Expect(response.Err).To(ContainSubstring("request body too large"))
+			Expect(driver.MountCallCount()).To(Equal(0))
+		})
+	})
+})
@@ -0,0 +1,104 @@
+package driverhttp
+
+import (
+	"errors"
+	"fmt"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/google/uuid"
+)
+
+// WithUnsafeErrorRedaction only lets SafeErrors leave the driver. Any other
+// error, which may hold server addresses, usernames or mount output, is
+// logged in full and replaced by a generic SafeError with a correlation id
+// that leads back to the log line.
+func WithUnsafeErrorRedaction() HandlerOption {
+	return func(c *handlerConfig) {
+		c.redactUnsafeErrors = true
+	}
+}
+
+type redactingDriver struct {
+	dockerdriver.Driver
+}
+
+func newRedactingDriver(driver dockerdriver.Driver) dockerdriver.Driver {
+	return &redactingDriver{Driver: driver}
+}
+
+func (d *redactingDriver) Activate(env dockerdriver.Env) dockerdriver.ActivateResponse {
+	response := d.Driver.Activate(env)
+	response.Err, response.ErrCode = redact(env.Logger(), response.Err, response.ErrCode)
+	return response
+}
+
+func (d *redactingDriver) Get(env dockerdriver.Env, getRequest dockerdriver.GetRequest) dockerdriver.GetResponse {
+	response := d.Driver.Get(env, getRequest)
+	response.Err, response.ErrCode = redact(env.Logger(), response.Err, response.ErrCode)
+	return response
+}
+
+func (d *redactingDriver) List(env dockerdriver.Env) dockerdriver.ListResponse {
+	response := d.Driver.List(env)
+	response.Err, response.ErrCode = redact(env.Logger(), response.Err, response.ErrCode)
+	return response
+}
+
+func (d *redactingDriver) Mount(env dockerdriver.Env, mountRequest dockerdriver.MountRequest) dockerdriver.MountResponse {
+	response := d.Driver.Mount(env, mountRequest)
+	response.Err, response.ErrCode = redact(env.Logger(), response.Err, response.ErrCode)
+	return response
+}
+
+func (d *redactingDriver) Path(env dockerdriver.Env, pathRequest dockerdriver.PathRequest) dockerdriver.PathResponse {
+	response := d.Driver.Path(env, pathRequest)
+	response.Err, response.ErrCode = redact(env.Logger(), response.Err, response.ErrCode)
+	return response
+}
+
+func (d *redactingDriver) Unmount(env dockerdriver.Env, unmountRequest dockerdriver.UnmountRequest) dockerdriver.ErrorResponse {
+	response := d.Driver.Unmount(env, unmountRequest)
+	response.Err, response.ErrCode = redact(env.Logger(), response.Err, response.ErrCode)
+	return response
+}
+
+func (d *redactingDriver) Create(env dockerdriver.Env, createRequest dockerdriver.CreateRequest) dockerdriver.ErrorResponse {
+	response := d.Driver.Create(env, createRequest)
+	response.Err, response.ErrCode = redact(env.Logger(), response.Err, response.ErrCode)
+	return response
+}
+
+func (d *redactingDriver) Remove(env dockerdriver.Env, removeRequest dockerdriver.RemoveRequest) dockerdriver.ErrorResponse {
+	response := d.Driver.Remove(env, removeRequest)
+	response.Err, response.ErrCode = redact(env.Logger(), response.Err, response.ErrCode)
+	return response
+}
+
+// redact classifies the error before replacing it, since the code is safe to
+// send but can't be recovered from the generic message.
+func redact(logger lager.Logger, message string, code dockerdriver.ErrorCode) (string, dockerdriver.ErrorCode) {
+	if message == "" {
+		return message, code
+	}
+	if _, ok := dockerdriver.ParseSafeError(message); ok {
+		return message, code
+	}
+
+	if code == "" {
+		code = dockerdriver.ClassifyError(message)
+	}
+
+	correlationID := uuid.NewString()
+	logger.Error("redacted-unsafe-error", errors.New(message), lager.Data{"correlation-id": correlationID, "code": code})
+
+	return redactedErrorMessage(correlationID), code
+}
+
+// redactedErrorMessage is the generic SafeError sent in place of an error
+// logged with correlationID.
+func redactedErrorMessage(correlationID string) string {
+	return dockerdriver.SafeErrorMessage(dockerdriver.SafeError{
+		SafeDescription: fmt.Sprintf("the volume driver failed; see the driver logs for correlation id %s", correlationID),
+	})
+}
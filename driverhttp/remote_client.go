@@ -5,12 +5,18 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"code.cloudfoundry.org/cfhttp/v2"
 	"code.cloudfoundry.org/clock"
 	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/drivertracing"
 	"code.cloudfoundry.org/goshims/http_wrap"
 	"code.cloudfoundry.org/lager/v3"
 	"code.cloudfoundry.org/tlsconfig"
@@ -36,18 +42,32 @@ func (r *reqFactory) Request() (*http.Request, error) {
 }
 
 type remoteClient struct {
-	HttpClient http_wrap.Client
-	reqGen     *rata.RequestGenerator
-	clock      clock.Clock
-	url        string
-	tls        *dockerdriver.TLSConfig
+	HttpClient  http_wrap.Client
+	reqGen      *rata.RequestGenerator
+	clock       clock.Clock
+	url         string
+	tls         *dockerdriver.TLSConfig
+	retryPolicy RetryPolicy
+	timeouts    Timeouts
+	breaker     *circuitBreaker
+	metrics     *requestMetrics
+	tracer      *drivertracing.Tracer
+	traceFormat drivertracing.Format
 }
 
-func NewRemoteClient(url string, tls *dockerdriver.TLSConfig) (*remoteClient, error) {
-	client := cfhttp.NewClient()
+type RemoteClientOption func(*remoteClient)
+
+func NewRemoteClient(url string, tls *dockerdriver.TLSConfig, opts ...RemoteClientOption) (*remoteClient, error) {
 	input_url := url
 
-	if tls != nil {
+	url, socketPath, err := resolveAddress(url, tls)
+	if err != nil {
+		return nil, err
+	}
+
+	var options []cfhttp.Option
+	// TLS is meaningless over a unix socket, so don't go loading certificates for one
+	if tls != nil && socketPath == "" {
 		tlsConfig, err := tlsconfig.Build(
 			tlsconfig.WithInternalServiceDefaults(),
 			tlsconfig.WithIdentityFromFile(tls.CertFile, tls.KeyFile),
@@ -58,23 +78,85 @@ func NewRemoteClient(url string, tls *dockerdriver.TLSConfig) (*remoteClient, er
 
 		tlsConfig.InsecureSkipVerify = tls.InsecureSkipVerify
 
-		client = cfhttp.NewClient(cfhttp.WithTLSConfig(tlsConfig))
+		options = append(options, cfhttp.WithTLSConfig(tlsConfig))
 	}
 
-	driver := NewRemoteClientWithClient(url, tls, client, clock.NewClock())
+	client := cfhttp.NewClient(options...)
+	if socketPath != "" {
+		client.Transport.(*http.Transport).DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			dialer := net.Dialer{Timeout: unixDialTimeout}
+			return dialer.DialContext(ctx, "unix", socketPath)
+		}
+	}
+
+	driver := NewRemoteClientWithClient(url, tls, client, clock.NewClock(), opts...)
 	driver.url = input_url
 	return driver, nil
 }
 
-func NewRemoteClientWithClient(url string, tls *dockerdriver.TLSConfig, client http_wrap.Client, clock clock.Clock) *remoteClient {
+func NewRemoteClientFromSpec(spec *dockerdriver.DriverSpec, opts ...RemoteClientOption) (*remoteClient, error) {
+	if spec == nil {
+		return nil, errors.New("driver spec is nil")
+	}
+	return NewRemoteClient(spec.Address, spec.TLSConfig, opts...)
+}
+
+const (
+	unixDialTimeout = 5 * time.Second
+	// the host is ignored when dialing a unix socket; this is the name docker uses
+	unixSocketHost = "plugin.sock"
+)
+
+// resolveAddress maps any of the address forms docker accepts for a plugin
+// (a socket path, unix://, tcp://, http(s):// or a bare host:port) onto the
+// base url for requests and, for unix sockets, the path of the socket to dial.
+func resolveAddress(address string, tls *dockerdriver.TLSConfig) (string, string, error) {
+	scheme := "http"
+	if tls != nil {
+		scheme = "https"
+	}
+
+	switch {
+	case address == "":
+		return "", "", errors.New("driver address is empty")
+	case strings.HasPrefix(address, "unix://"):
+		socketPath := strings.TrimPrefix(address, "unix://")
+		if socketPath == "" {
+			return "", "", fmt.Errorf("invalid unix socket address: %q", address)
+		}
+		return "http://" + unixSocketHost, socketPath, nil
+	case strings.HasPrefix(address, "/") || filepath.Ext(address) == ".sock":
+		return "http://" + unixSocketHost, address, nil
+	case strings.HasPrefix(address, "tcp://"):
+		host := strings.TrimPrefix(address, "tcp://")
+		if host == "" {
+			return "", "", fmt.Errorf("invalid tcp address: %q", address)
+		}
+		return scheme + "://" + host, "", nil
+	case strings.HasPrefix(address, "http://") || strings.HasPrefix(address, "https://"):
+		return address, "", nil
+	case strings.Contains(address, "://"):
+		return "", "", fmt.Errorf("unsupported driver address scheme: %q", address)
+	default:
+		return scheme + "://" + address, "", nil
+	}
+}
+
+func NewRemoteClientWithClient(url string, tls *dockerdriver.TLSConfig, client http_wrap.Client, clock clock.Clock, opts ...RemoteClientOption) *remoteClient {
 	driver := remoteClient{
-		HttpClient: client,
-		reqGen:     rata.NewRequestGenerator(url, dockerdriver.Routes),
-		clock:      clock,
+		HttpClient:  client,
+		reqGen:      rata.NewRequestGenerator(url, dockerdriver.Routes),
+		clock:       clock,
+		timeouts:    DefaultTimeouts(),
+		traceFormat: drivertracing.FormatW3C,
 	}
 
 	driver.tls = tls
 
+	for _, opt := range opts {
+		opt(&driver)
+	}
+
 	return &driver
 }
 
@@ -105,7 +187,16 @@ func (r *remoteClient) Matches(loggerIn lager.Logger, url string, tls *dockerdri
 	return string(tls1) == string(tls2)
 }
 
+// CloseIdleConnections closes any keep-alive connections the client is not
+// using, for when the client is being discarded.
+func (r *remoteClient) CloseIdleConnections() {
+	if closer, ok := r.HttpClient.(idleConnectionCloser); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
 func (r *remoteClient) Activate(env dockerdriver.Env) dockerdriver.ActivateResponse {
+	env = envWithRequestID(env)
 	logger := env.Logger().Session("activate")
 	logger.Info("start")
 	defer logger.Info("end")
@@ -115,7 +206,7 @@ func (r *remoteClient) Activate(env dockerdriver.Env) dockerdriver.ActivateRespo
 	response, err := r.do(env.Context(), logger, request)
 	if err != nil {
 		logger.Error("failed-activate", err)
-		return dockerdriver.ActivateResponse{Err: err.Error()}
+		return dockerdriver.ActivateResponse{Err: err.Error(), ErrCode: errorCode(err)}
 	}
 
 	if response == nil {
@@ -132,6 +223,7 @@ func (r *remoteClient) Activate(env dockerdriver.Env) dockerdriver.ActivateRespo
 }
 
 func (r *remoteClient) Create(env dockerdriver.Env, createRequest dockerdriver.CreateRequest) dockerdriver.ErrorResponse {
+	env = envWithRequestID(env)
 	logger := env.Logger().Session("create", lager.Data{"create_request.Name": createRequest.Name})
 	logger.Info("start")
 	defer logger.Info("end")
@@ -147,7 +239,7 @@ func (r *remoteClient) Create(env dockerdriver.Env, createRequest dockerdriver.C
 	response, err := r.do(env.Context(), logger, request)
 	if err != nil {
 		logger.Error("failed-creating-volume", err)
-		return dockerdriver.ErrorResponse{Err: err.Error()}
+		return dockerdriver.ErrorResponse{Err: err.Error(), ErrCode: errorCode(err)}
 	}
 
 	var remoteError dockerdriver.ErrorResponse
@@ -160,10 +252,11 @@ func (r *remoteClient) Create(env dockerdriver.Env, createRequest dockerdriver.C
 		return dockerdriver.ErrorResponse{Err: err.Error()}
 	}
 
-	return dockerdriver.ErrorResponse{}
+	return remoteError
 }
 
 func (r *remoteClient) List(env dockerdriver.Env) dockerdriver.ListResponse {
+	env = envWithRequestID(env)
 	logger := env.Logger().Session("remoteclient-list")
 	logger.Info("start")
 	defer logger.Info("end")
@@ -173,7 +266,7 @@ func (r *remoteClient) List(env dockerdriver.Env) dockerdriver.ListResponse {
 	response, err := r.do(env.Context(), logger, request)
 	if err != nil {
 		logger.Error("failed-list", err)
-		return dockerdriver.ListResponse{Err: err.Error()}
+		return dockerdriver.ListResponse{Err: err.Error(), ErrCode: errorCode(err)}
 	}
 
 	if response == nil {
@@ -190,6 +283,7 @@ func (r *remoteClient) List(env dockerdriver.Env) dockerdriver.ListResponse {
 }
 
 func (r *remoteClient) Mount(env dockerdriver.Env, mountRequest dockerdriver.MountRequest) dockerdriver.MountResponse {
+	env = envWithRequestID(env)
 	logger := env.Logger().Session("remoteclient-mount", lager.Data{"mount_request": mountRequest})
 	logger.Info("start")
 	defer logger.Info("end")
@@ -205,7 +299,7 @@ func (r *remoteClient) Mount(env dockerdriver.Env, mountRequest dockerdriver.Mou
 	response, err := r.do(env.Context(), logger, request)
 	if err != nil {
 		logger.Error("failed-mounting-volume", err)
-		return dockerdriver.MountResponse{Err: err.Error()}
+		return dockerdriver.MountResponse{Err: err.Error(), ErrCode: errorCode(err)}
 	}
 
 	if response == nil {
@@ -222,6 +316,7 @@ func (r *remoteClient) Mount(env dockerdriver.Env, mountRequest dockerdriver.Mou
 }
 
 func (r *remoteClient) Path(env dockerdriver.Env, pathRequest dockerdriver.PathRequest) dockerdriver.PathResponse {
+	env = envWithRequestID(env)
 	logger := env.Logger().Session("path")
 	logger.Info("start")
 	defer logger.Info("end")
@@ -237,7 +332,7 @@ func (r *remoteClient) Path(env dockerdriver.Env, pathRequest dockerdriver.PathR
 	response, err := r.do(env.Context(), logger, request)
 	if err != nil {
 		logger.Error("failed-volume-path", err)
-		return dockerdriver.PathResponse{Err: err.Error()}
+		return dockerdriver.PathResponse{Err: err.Error(), ErrCode: errorCode(err)}
 	}
 
 	if response == nil {
@@ -254,7 +349,8 @@ func (r *remoteClient) Path(env dockerdriver.Env, pathRequest dockerdriver.PathR
 }
 
 func (r *remoteClient) Unmount(env dockerdriver.Env, unmountRequest dockerdriver.UnmountRequest) dockerdriver.ErrorResponse {
-	logger := env.Logger().Session("unmount")
+	env = envWithRequestID(env)
+	logger := env.Logger().Session("unmount", lager.Data{"unmount_request": unmountRequest})
 	logger.Info("start")
 	defer logger.Info("end")
 
@@ -269,7 +365,7 @@ func (r *remoteClient) Unmount(env dockerdriver.Env, unmountRequest dockerdriver
 	response, err := r.do(env.Context(), logger, request)
 	if err != nil {
 		logger.Error("failed-unmounting-volume", err)
-		return dockerdriver.ErrorResponse{Err: err.Error()}
+		return dockerdriver.ErrorResponse{Err: err.Error(), ErrCode: errorCode(err)}
 	}
 
 	if response == nil {
@@ -285,6 +381,7 @@ func (r *remoteClient) Unmount(env dockerdriver.Env, unmountRequest dockerdriver
 }
 
 func (r *remoteClient) Remove(env dockerdriver.Env, removeRequest dockerdriver.RemoveRequest) dockerdriver.ErrorResponse {
+	env = envWithRequestID(env)
 	logger := env.Logger().Session("remove")
 	logger.Info("start")
 	defer logger.Info("end")
@@ -300,7 +397,7 @@ func (r *remoteClient) Remove(env dockerdriver.Env, removeRequest dockerdriver.R
 	response, err := r.do(env.Context(), logger, request)
 	if err != nil {
 		logger.Error("failed-removing-volume", err)
-		return dockerdriver.ErrorResponse{Err: err.Error()}
+		return dockerdriver.ErrorResponse{Err: err.Error(), ErrCode: errorCode(err)}
 	}
 
 	if response == nil {
@@ -317,6 +414,7 @@ func (r *remoteClient) Remove(env dockerdriver.Env, removeRequest dockerdriver.R
 }
 
 func (r *remoteClient) Get(env dockerdriver.Env, getRequest dockerdriver.GetRequest) dockerdriver.GetResponse {
+	env = envWithRequestID(env)
 	logger := env.Logger().Session("get")
 	logger.Info("start")
 	defer logger.Info("end")
@@ -332,7 +430,7 @@ func (r *remoteClient) Get(env dockerdriver.Env, getRequest dockerdriver.GetRequ
 	response, err := r.do(env.Context(), logger, request)
 	if err != nil {
 		logger.Error("failed-getting-volume", err)
-		return dockerdriver.GetResponse{Err: err.Error()}
+		return dockerdriver.GetResponse{Err: err.Error(), ErrCode: errorCode(err)}
 	}
 
 	if response == nil {
@@ -349,6 +447,7 @@ func (r *remoteClient) Get(env dockerdriver.Env, getRequest dockerdriver.GetRequ
 }
 
 func (r *remoteClient) Capabilities(env dockerdriver.Env) dockerdriver.CapabilitiesResponse {
+	env = envWithRequestID(env)
 	logger := env.Logger().Session("capabilities")
 	logger.Info("start")
 	defer logger.Info("end")
@@ -380,6 +479,108 @@ func (r *remoteClient) GetVoldriver() dockerdriver.Driver {
 }
 
 func (r *remoteClient) do(ctx context.Context, logger lager.Logger, requestFactory *reqFactory) ([]byte, error) {
+	if r.tracer == nil {
+		return r.doWithMetrics(ctx, logger.WithData(drivertracing.LogData(ctx)), requestFactory)
+	}
+
+	ctx, span := r.tracer.Start(ctx, "remoteclient-"+requestFactory.route, drivertracing.SpanKindClient)
+	defer span.End()
+	span.SetAttribute("route", requestFactory.route)
+	span.SetAttribute("driver-url", r.url)
+
+	data, err := r.doWithMetrics(ctx, logger.WithData(drivertracing.LogData(ctx)), requestFactory)
+	if err != nil {
+		span.SetError(err.Error())
+	}
+	return data, err
+}
+
+func (r *remoteClient) doWithMetrics(ctx context.Context, logger lager.Logger, requestFactory *reqFactory) ([]byte, error) {
+	if r.metrics == nil {
+		return r.doWithBreaker(ctx, logger, requestFactory)
+	}
+
+	done := r.metrics.start(requestFactory.route, r.clock.Now)
+	data, err := r.doWithBreaker(ctx, logger, requestFactory)
+	if err != nil {
+		done(err.Error(), errorCode(err))
+	} else {
+		done("", "")
+	}
+	return data, err
+}
+
+func (r *remoteClient) doWithBreaker(ctx context.Context, logger lager.Logger, requestFactory *reqFactory) ([]byte, error) {
+	if r.breaker == nil {
+		return r.doWithRetries(ctx, logger, requestFactory)
+	}
+
+	if err := r.breaker.allow(ctx, logger, r.probe(ctx, logger)); err != nil {
+		logger.Error("circuit-breaker-rejected-request", err)
+		return nil, err
+	}
+
+	data, err := r.doWithRetries(ctx, logger, requestFactory)
+	r.breaker.record(logger, isBreakerFailure(ctx, err))
+	return data, err
+}
+
+func (r *remoteClient) doWithRetries(ctx context.Context, logger lager.Logger, requestFactory *reqFactory) ([]byte, error) {
+	ctx, cancel := r.timeouts.withTimeout(ctx, requestFactory.route)
+	defer cancel()
+
+	attempts := r.retryPolicy.attemptsFor(requestFactory.route)
+
+	for attempt := 1; ; attempt++ {
+		logger.Debug("attempt", lager.Data{"attempt": attempt, "max-attempts": attempts})
+
+		data, err := r.doOnce(ctx, logger, requestFactory)
+		if err != nil {
+			if timeoutErr := timeoutError(ctx); timeoutErr != nil {
+				logger.Error("request-timed-out", timeoutErr)
+				return data, timeoutErr
+			}
+		}
+		if err == nil || attempt >= attempts || !isConnectionError(ctx, err) {
+			return data, err
+		}
+
+		backoff := r.retryPolicy.backoff(attempt)
+		logger.Info("retrying-after-connection-error", lager.Data{"attempt": attempt, "max-attempts": attempts, "backoff": backoff.String(), "error": err.Error()})
+
+		timer := r.clock.NewTimer(backoff)
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			timer.Stop()
+			if timeoutErr := timeoutError(ctx); timeoutErr != nil {
+				logger.Error("request-timed-out", timeoutErr)
+				return data, timeoutErr
+			}
+			return data, err
+		}
+	}
+}
+
+// errorCode classifies the errors the client raises itself; errors from the
+// driver carry their own code.
+func errorCode(err error) dockerdriver.ErrorCode {
+	var connErr *connectionError
+	switch {
+	case errors.Is(err, ErrCircuitOpen), errors.As(err, &connErr):
+		return dockerdriver.ErrCodeUnavailable
+	case errors.Is(err, ErrTimeout):
+		return dockerdriver.ErrCodeTimeout
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.Driver == nil {
+		return statusErr.code()
+	}
+	return dockerdriver.ErrorCodeOf(err)
+}
+
+func (r *remoteClient) doOnce(ctx context.Context, logger lager.Logger, requestFactory *reqFactory) ([]byte, error) {
 
 	var data []byte
 
@@ -389,14 +590,32 @@ func (r *remoteClient) do(ctx context.Context, logger lager.Logger, requestFacto
 		return data, err
 	}
 	request = request.WithContext(ctx)
+	drivertracing.Inject(ctx, request.Header, r.traceFormat)
+	if requestID, ok := RequestIDFromContext(ctx); ok {
+		request.Header.Set(RequestIDHeader, requestID)
+	}
 
 	response, err := r.HttpClient.Do(request)
 	if err != nil {
 		logger.Error("request-failed", err)
-		return data, err
+		return data, &connectionError{err}
 	}
+	defer response.Body.Close()
 	logger.Debug("response", lager.Data{"response": response.Status})
 
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		err := newStatusError(response)
+		logger.Error("unexpected-http-status", err, lager.Data{"status": response.StatusCode})
+		return data, err
+	}
+
+	if !isJSONContentType(response.Header.Get("Content-Type")) {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, maxErrorBodyBytes))
+		err := newContentError(response, body)
+		logger.Error("unexpected-content-type", err)
+		return data, err
+	}
+
 	data, err = io.ReadAll(response.Body)
 	if err != nil {
 		return data, err
@@ -405,11 +624,11 @@ func (r *remoteClient) do(ctx context.Context, logger lager.Logger, requestFacto
 	var remoteErrorResponse dockerdriver.ErrorResponse
 	if err := json.Unmarshal(data, &remoteErrorResponse); err != nil {
 		logger.Error("failed-parsing-http-response-body", err)
-		return data, err
+		return data, newContentError(response, data)
 	}
 
 	if remoteErrorResponse.Err != "" {
-		return data, errors.New(remoteErrorResponse.Err)
+		return data, newDriverError(remoteErrorResponse)
 	}
 
 	return data, nil
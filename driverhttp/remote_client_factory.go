@@ -1,7 +1,11 @@
 package driverhttp
 
 import (
+	"container/list"
+	"sync"
+
 	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/lager/v3"
 )
 
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -generate
@@ -10,12 +14,112 @@ type RemoteClientFactory interface {
 	NewRemoteClient(url string, tls *dockerdriver.TLSConfig) (dockerdriver.Driver, error)
 }
 
-func NewRemoteClientFactory() RemoteClientFactory {
-	return &remoteClientFactory{}
+func NewRemoteClientFactory(opts ...RemoteClientOption) RemoteClientFactory {
+	return &remoteClientFactory{opts: opts}
+}
+
+type remoteClientFactory struct {
+	opts []RemoteClientOption
+}
+
+func (f *remoteClientFactory) NewRemoteClient(url string, tls *dockerdriver.TLSConfig) (dockerdriver.Driver, error) {
+	return NewRemoteClient(url, tls, f.opts...)
+}
+
+type CacheStats struct {
+	Size      int
+	Hits      int
+	Misses    int
+	Evictions int
+}
+
+type CachingRemoteClientFactory interface {
+	RemoteClientFactory
+	Stats() CacheStats
+}
+
+type idleConnectionCloser interface {
+	CloseIdleConnections()
+}
+
+type cacheEntry struct {
+	url    string
+	driver dockerdriver.Driver
+}
+
+// NewCachingRemoteClientFactory returns a factory that hands back the same
+// client for the same address and TLS config, so callers that resolve a driver
+// per request share one connection pool per driver. A client whose TLS config
+// no longer matches is replaced, and when maxSize (if positive) is exceeded
+// the least recently used client is evicted. Evicted clients have their idle
+// connections closed.
+func NewCachingRemoteClientFactory(logger lager.Logger, maxSize int, factory RemoteClientFactory) CachingRemoteClientFactory {
+	return &cachingRemoteClientFactory{
+		logger:  logger.Session("caching-remote-client-factory"),
+		maxSize: maxSize,
+		factory: factory,
+		entries: map[string]*list.Element{},
+		lru:     list.New(),
+	}
+}
+
+type cachingRemoteClientFactory struct {
+	logger  lager.Logger
+	maxSize int
+	factory RemoteClientFactory
+
+	lock    sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	stats   CacheStats
+}
+
+func (c *cachingRemoteClientFactory) NewRemoteClient(url string, tls *dockerdriver.TLSConfig) (dockerdriver.Driver, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if element, found := c.entries[url]; found {
+		entry := element.Value.(*cacheEntry)
+		if matchable, ok := entry.driver.(dockerdriver.MatchableDriver); ok && matchable.Matches(c.logger, url, tls) {
+			c.stats.Hits++
+			c.lru.MoveToFront(element)
+			return entry.driver, nil
+		}
+
+		c.logger.Info("spec-changed", lager.Data{"url": url})
+		c.evict(element)
+	}
+
+	c.stats.Misses++
+	driver, err := c.factory.NewRemoteClient(url, tls)
+	if err != nil {
+		return nil, err
+	}
+
+	c.entries[url] = c.lru.PushFront(&cacheEntry{url: url, driver: driver})
+	if c.maxSize > 0 && c.lru.Len() > c.maxSize {
+		c.evict(c.lru.Back())
+	}
+
+	return driver, nil
+}
+
+func (c *cachingRemoteClientFactory) Stats() CacheStats {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	stats := c.stats
+	stats.Size = c.lru.Len()
+	return stats
 }
 
-type remoteClientFactory struct{}
+func (c *cachingRemoteClientFactory) evict(element *list.Element) {
+	entry := c.lru.Remove(element).(*cacheEntry)
+	delete(c.entries, entry.url)
+	c.stats.Evictions++
+	c.logger.Info("evicted", lager.Data{"url": entry.url})
 
-func (*remoteClientFactory) NewRemoteClient(url string, tls *dockerdriver.TLSConfig) (dockerdriver.Driver, error) {
-	return NewRemoteClient(url, tls)
+	if closer, ok := entry.driver.(idleConnectionCloser); ok {
+		closer.CloseIdleConnections()
+	}
 }
@@ -0,0 +1,110 @@
+package driverhttp_test
+
+import (
+	"errors"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/dockerdriverfakes"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager/v3"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type closableDriver struct {
+	*dockerdriverfakes.FakeMatchableDriver
+	url        string
+	tls        *dockerdriver.TLSConfig
+	closedIdle int
+}
+
+func (c *closableDriver) Matches(_ lager.Logger, url string, tls *dockerdriver.TLSConfig) bool {
+	return url == c.url && tls == c.tls
+}
+
+func (c *closableDriver) CloseIdleConnections() {
+	c.closedIdle++
+}
+
+var _ = Describe("CachingRemoteClientFactory", func() {
+	var (
+		fakeFactory *dockerdriverfakes.FakeRemoteClientFactory
+		created     []*closableDriver
+		factory     driverhttp.CachingRemoteClientFactory
+	)
+
+	BeforeEach(func() {
+		created = nil
+		fakeFactory = &dockerdriverfakes.FakeRemoteClientFactory{}
+		fakeFactory.NewRemoteClientStub = func(url string, tls *dockerdriver.TLSConfig) (dockerdriver.Driver, error) {
+			driver := &closableDriver{FakeMatchableDriver: &dockerdriverfakes.FakeMatchableDriver{}, url: url, tls: tls}
+			created = append(created, driver)
+			return driver, nil
+		}
+
+		factory = driverhttp.NewCachingRemoteClientFactory(lagertest.NewTestLogger("factory-test"), 2, fakeFactory)
+	})
+
+	It("should reuse the client for the same address and tls config", func() {
+		first, err := factory.NewRemoteClient("http://some-driver", nil)
+		Expect(err).NotTo(HaveOccurred())
+		second, err := factory.NewRemoteClient("http://some-driver", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(second).To(BeIdenticalTo(first))
+		Expect(fakeFactory.NewRemoteClientCallCount()).To(Equal(1))
+		Expect(factory.Stats()).To(Equal(driverhttp.CacheStats{Size: 1, Hits: 1, Misses: 1}))
+	})
+
+	It("should replace the client when the tls config changes", func() {
+		first, err := factory.NewRemoteClient("http://some-driver", nil)
+		Expect(err).NotTo(HaveOccurred())
+		second, err := factory.NewRemoteClient("http://some-driver", &dockerdriver.TLSConfig{CAFile: "/ca.crt"})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(second).NotTo(BeIdenticalTo(first))
+		Expect(created[0].closedIdle).To(Equal(1))
+		Expect(factory.Stats()).To(Equal(driverhttp.CacheStats{Size: 1, Misses: 2, Evictions: 1}))
+	})
+
+	It("should evict the least recently used client when full", func() {
+		_, err := factory.NewRemoteClient("http://first", nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = factory.NewRemoteClient("http://second", nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = factory.NewRemoteClient("http://first", nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = factory.NewRemoteClient("http://third", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(created[1].url).To(Equal("http://second"))
+		Expect(created[1].closedIdle).To(Equal(1))
+		Expect(created[0].closedIdle).To(Equal(0))
+		Expect(factory.Stats()).To(Equal(driverhttp.CacheStats{Size: 2, Hits: 1, Misses: 3, Evictions: 1}))
+	})
+
+	It("should not cache failures", func() {
+		fakeFactory.NewRemoteClientStub = nil
+		fakeFactory.NewRemoteClientReturns(nil, errors.New("bad tls"))
+
+		_, err := factory.NewRemoteClient("http://some-driver", nil)
+		Expect(err).To(MatchError("bad tls"))
+		Expect(factory.Stats().Size).To(Equal(0))
+	})
+
+	Context("when wrapping real remote clients", func() {
+		BeforeEach(func() {
+			factory = driverhttp.NewCachingRemoteClientFactory(lagertest.NewTestLogger("factory-test"), 0, driverhttp.NewRemoteClientFactory())
+		})
+
+		It("should reuse them", func() {
+			first, err := factory.NewRemoteClient("http://127.0.0.1:7589", nil)
+			Expect(err).NotTo(HaveOccurred())
+			second, err := factory.NewRemoteClient("http://127.0.0.1:7589", nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(second).To(BeIdenticalTo(first))
+		})
+	})
+})
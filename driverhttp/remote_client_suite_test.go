@@ -3,10 +3,7 @@ package driverhttp_test
 import (
 	"fmt"
 	"io"
-	"os/exec"
-	"path"
 	"testing"
-	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -21,22 +18,17 @@ func TestDriver(t *testing.T) {
 }
 
 var _ = SynchronizedBeforeSuite(func() []byte {
-	dirname := GinkgoT().TempDir()
-	cmd := exec.Command("go", "install", "-race", "code.cloudfoundry.org/localdriver/cmd/localdriver@latest")
-	cmd.Env = append(cmd.Environ(), fmt.Sprintf("GOBIN=%s", dirname))
-	cmd.Dir = dirname
-
-	session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+	path, err := gexec.Build("code.cloudfoundry.org/dockerdriver/cmd/localdriver", "-race")
 	Expect(err).NotTo(HaveOccurred())
-	Eventually(session).WithTimeout(time.Hour).Should(gexec.Exit(0))
-
-	localDriverPath = path.Join(dirname, "localdriver")
-	Expect(localDriverPath).To(BeAnExistingFile())
-	return []byte(localDriverPath)
+	return []byte(path)
 }, func(pathsByte []byte) {
 	localDriverPath = string(pathsByte)
 })
 
+var _ = SynchronizedAfterSuite(func() {}, func() {
+	gexec.CleanupBuildArtifacts()
+})
+
 // testing support types:
 
 type errCloser struct{ io.Reader }
@@ -47,3 +39,13 @@ func (errCloser) Read(p []byte) (n int, err error) { return 0, fmt.Errorf("any")
 type stringCloser struct{ io.Reader }
 
 func (stringCloser) Close() error { return nil }
+
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
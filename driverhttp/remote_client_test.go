@@ -3,14 +3,21 @@ package driverhttp_test
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"os"
 	"os/exec"
 	"path"
+	"strings"
 	"time"
 
 	"code.cloudfoundry.org/clock/fakeclock"
 	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/dockerdriverfakes"
 	"code.cloudfoundry.org/dockerdriver/driverhttp"
 	"code.cloudfoundry.org/goshims/http_wrap/http_fake"
 	"code.cloudfoundry.org/lager/v3"
@@ -151,6 +158,17 @@ var _ = Describe("RemoteClient", func() {
 			Expect(mountResponse.Mountpoint).To(Equal("somePath"))
 		})
 
+		It("should send the mount ID to the driver", func() {
+			httpClient.DoReturns(validHttpMountResponse, nil)
+
+			driver.Mount(env, dockerdriver.MountRequest{Name: "fake-volume", ID: "some-container-id"})
+
+			Expect(httpClient.DoCallCount()).To(Equal(1))
+			var mountRequest dockerdriver.MountRequest
+			Expect(json.NewDecoder(httpClient.DoArgsForCall(0).Body).Decode(&mountRequest)).To(Succeed())
+			Expect(mountRequest).To(Equal(dockerdriver.MountRequest{Name: "fake-volume", ID: "some-container-id"}))
+		})
+
 		It("should return mount point", func() {
 			httpClient.DoReturns(validHttpPathResponse, nil)
 
@@ -176,6 +194,46 @@ var _ = Describe("RemoteClient", func() {
 			Expect(unmountResponse.Err).To(Equal(""))
 		})
 
+		It("should return the volume status from get", func() {
+			httpClient.DoReturns(&http.Response{
+				StatusCode: driverhttp.StatusOK,
+				Body:       stringCloser{bytes.NewBufferString(`{"Volume":{"Name":"fake-volume","Status":{"health":"healthy","source":"some-server:/share"}}}`)},
+			}, nil)
+
+			getResponse := driver.Get(env, dockerdriver.GetRequest{Name: "fake-volume"})
+
+			Expect(getResponse.Err).To(Equal(""))
+			Expect(getResponse.Volume.Health()).To(Equal(dockerdriver.VolumeHealthy))
+			Expect(getResponse.Volume.Source()).To(Equal("some-server:/share"))
+		})
+
+		It("should return the volume status from list", func() {
+			httpClient.DoReturns(&http.Response{
+				StatusCode: driverhttp.StatusOK,
+				Body:       stringCloser{bytes.NewBufferString(`{"Volumes":[{"Name":"fake-volume","Status":{"last_error":"some error"}}]}`)},
+			}, nil)
+
+			listResponse := driver.List(env)
+
+			Expect(listResponse.Err).To(Equal(""))
+			Expect(listResponse.Volumes).To(HaveLen(1))
+			Expect(listResponse.Volumes[0].LastError()).To(Equal("some error"))
+		})
+
+		It("should send the mount ID when unmounting", func() {
+			httpClient.DoReturns(&http.Response{
+				StatusCode: driverhttp.StatusOK,
+				Body:       stringCloser{bytes.NewBufferString("{\"Err\":\"\"}")},
+			}, nil)
+
+			driver.Unmount(env, dockerdriver.UnmountRequest{Name: "fake-volume", ID: "some-container-id"})
+
+			Expect(httpClient.DoCallCount()).To(Equal(1))
+			var unmountRequest dockerdriver.UnmountRequest
+			Expect(json.NewDecoder(httpClient.DoArgsForCall(0).Body).Decode(&unmountRequest)).To(Succeed())
+			Expect(unmountRequest).To(Equal(dockerdriver.UnmountRequest{Name: "fake-volume", ID: "some-container-id"}))
+		})
+
 		It("should be able to activate", func() {
 			httpClient.DoReturns(validHttpActivateResponse, nil)
 
@@ -236,6 +294,66 @@ var _ = Describe("RemoteClient", func() {
 
 	})
 
+	Context("when the response is not a driver response", func() {
+		var body *closeRecorder
+
+		It("should report a non-2xx status", func() {
+			body = &closeRecorder{Reader: bytes.NewBufferString("<html>bad gateway</html>")}
+			httpClient.DoReturns(&http.Response{StatusCode: http.StatusBadGateway, Status: "502 Bad Gateway", Body: body}, nil)
+
+			mountResponse := driver.Mount(env, dockerdriver.MountRequest{Name: "fake-volume"})
+
+			Expect(mountResponse.Err).To(ContainSubstring("502 Bad Gateway"))
+			Expect(mountResponse.Err).To(ContainSubstring("bad gateway"))
+			Expect(mountResponse.ErrCode).To(Equal(dockerdriver.ErrCodeUnavailable))
+			Expect(body.closed).To(BeTrue())
+		})
+
+		It("should use a docker style error sent with a non-2xx status", func() {
+			body = &closeRecorder{Reader: bytes.NewBufferString(`{"Err":"volume is busy"}`)}
+			httpClient.DoReturns(&http.Response{StatusCode: http.StatusInternalServerError, Body: body}, nil)
+
+			unmountResponse := driver.Unmount(env, dockerdriver.UnmountRequest{Name: "fake-volume"})
+
+			Expect(unmountResponse.Err).To(Equal("volume is busy"))
+			Expect(unmountResponse.ErrCode).To(Equal(dockerdriver.ErrCodeInUse))
+		})
+
+		It("should report content that is not json", func() {
+			body = &closeRecorder{Reader: bytes.NewBufferString(`{"Mountpoint":"somePath"}`)}
+			httpClient.DoReturns(&http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": []string{"text/html"}},
+				Body:       body,
+			}, nil)
+
+			mountResponse := driver.Mount(env, dockerdriver.MountRequest{Name: "fake-volume"})
+
+			Expect(mountResponse.Err).To(ContainSubstring(`non-json content of type "text/html"`))
+			Expect(mountResponse.Mountpoint).To(BeEmpty())
+			Expect(body.closed).To(BeTrue())
+		})
+
+		It("should only read the start of an error body", func() {
+			body = &closeRecorder{Reader: strings.NewReader(strings.Repeat("x", 1024*1024))}
+			httpClient.DoReturns(&http.Response{StatusCode: http.StatusInternalServerError, Body: body}, nil)
+
+			pathResponse := driver.Path(env, dockerdriver.PathRequest{Name: "fake-volume"})
+
+			Expect(len(pathResponse.Err)).To(BeNumerically("<", 5*1024))
+		})
+
+		It("should close the body of a successful response", func() {
+			body = &closeRecorder{Reader: bytes.NewBufferString(`{"Mountpoint":"somePath"}`)}
+			httpClient.DoReturns(&http.Response{StatusCode: http.StatusOK, Body: body}, nil)
+
+			pathResponse := driver.Path(env, dockerdriver.PathRequest{Name: "fake-volume"})
+
+			Expect(pathResponse.Mountpoint).To(Equal("somePath"))
+			Expect(body.closed).To(BeTrue())
+		})
+	})
+
 	Context("when the http transport fails and the transport is TCP", func() {
 
 		BeforeEach(func() {
@@ -292,6 +410,335 @@ var _ = Describe("RemoteClient", func() {
 
 	})
 
+	Context("when a retry policy is configured", func() {
+		var validHttpGetResponse func() *http.Response
+
+		BeforeEach(func() {
+			driver = driverhttp.NewRemoteClientWithClient("http://127.0.0.1:8080", nil, httpClient, fakeClock, driverhttp.WithRetryPolicy(driverhttp.RetryPolicy{
+				MaxAttempts:    3,
+				InitialBackoff: time.Second,
+				MaxBackoff:     time.Minute,
+				Multiplier:     2,
+			}))
+
+			validHttpGetResponse = func() *http.Response {
+				return &http.Response{
+					StatusCode: driverhttp.StatusOK,
+					Body:       stringCloser{bytes.NewBufferString(`{"Volume":{"Name":"fake-volume"}}`)},
+				}
+			}
+		})
+
+		It("should retry a get that could not reach the driver", func() {
+			httpClient.DoReturnsOnCall(0, nil, fmt.Errorf("connection refused"))
+			httpClient.DoReturnsOnCall(1, validHttpGetResponse(), nil)
+
+			responses := make(chan dockerdriver.GetResponse)
+			go func() {
+				responses <- driver.Get(env, dockerdriver.GetRequest{Name: "fake-volume"})
+			}()
+
+			fakeClock.WaitForWatcherAndIncrement(time.Second)
+
+			var getResponse dockerdriver.GetResponse
+			Eventually(responses).Should(Receive(&getResponse))
+			Expect(getResponse.Err).To(BeEmpty())
+			Expect(getResponse.Volume.Name).To(Equal("fake-volume"))
+			Expect(httpClient.DoCallCount()).To(Equal(2))
+			Expect(testLogger.(*lagertest.TestLogger).LogMessages()).To(ContainElement(ContainSubstring("retrying-after-connection-error")))
+		})
+
+		It("should back off exponentially and give up after the maximum attempts", func() {
+			httpClient.DoReturns(nil, fmt.Errorf("connection refused"))
+
+			responses := make(chan dockerdriver.PathResponse)
+			go func() {
+				responses <- driver.Path(env, dockerdriver.PathRequest{Name: "fake-volume"})
+			}()
+
+			fakeClock.WaitForWatcherAndIncrement(time.Second)
+			Eventually(httpClient.DoCallCount).Should(Equal(2))
+
+			fakeClock.WaitForWatcherAndIncrement(time.Second)
+			Consistently(httpClient.DoCallCount).Should(Equal(2))
+			fakeClock.Increment(time.Second)
+
+			var pathResponse dockerdriver.PathResponse
+			Eventually(responses).Should(Receive(&pathResponse))
+			Expect(pathResponse.Err).To(Equal("connection refused"))
+			Expect(httpClient.DoCallCount()).To(Equal(3))
+		})
+
+		It("should not retry errors reported by the driver", func() {
+			httpClient.DoReturns(&http.Response{
+				StatusCode: driverhttp.StatusOK,
+				Body:       stringCloser{bytes.NewBufferString(`{"Err":"volume does not exist"}`)},
+			}, nil)
+
+			getResponse := driver.Get(env, dockerdriver.GetRequest{Name: "fake-volume"})
+
+			Expect(getResponse.Err).To(Equal("volume does not exist"))
+			Expect(httpClient.DoCallCount()).To(Equal(1))
+		})
+
+		It("should not retry a mount unless asked to", func() {
+			httpClient.DoReturns(nil, fmt.Errorf("connection refused"))
+
+			mountResponse := driver.Mount(env, dockerdriver.MountRequest{Name: "fake-volume"})
+
+			Expect(mountResponse.Err).To(Equal("connection refused"))
+			Expect(httpClient.DoCallCount()).To(Equal(1))
+		})
+
+		It("should stop waiting when the context is cancelled", func() {
+			httpClient.DoReturns(nil, fmt.Errorf("connection refused"))
+			cancelCtx, cancel := context.WithCancel(ctx)
+
+			responses := make(chan dockerdriver.ListResponse)
+			go func() {
+				responses <- driver.List(driverhttp.NewHttpDriverEnv(testLogger, cancelCtx))
+			}()
+
+			Eventually(fakeClock.WatcherCount).Should(Equal(1))
+			cancel()
+
+			var listResponse dockerdriver.ListResponse
+			Eventually(responses).Should(Receive(&listResponse))
+			Expect(listResponse.Err).NotTo(BeEmpty())
+			Expect(httpClient.DoCallCount()).To(Equal(1))
+		})
+
+		Context("when mount retries are enabled", func() {
+			BeforeEach(func() {
+				driver = driverhttp.NewRemoteClientWithClient("http://127.0.0.1:8080", nil, httpClient, fakeClock, driverhttp.WithRetryPolicy(driverhttp.RetryPolicy{
+					MaxAttempts:    2,
+					InitialBackoff: time.Second,
+					RetryMount:     true,
+				}))
+			})
+
+			It("should retry the mount", func() {
+				httpClient.DoReturnsOnCall(0, nil, fmt.Errorf("connection refused"))
+				httpClient.DoReturnsOnCall(1, validHttpMountResponse, nil)
+
+				responses := make(chan dockerdriver.MountResponse)
+				go func() {
+					responses <- driver.Mount(env, dockerdriver.MountRequest{Name: "fake-volume"})
+				}()
+
+				fakeClock.WaitForWatcherAndIncrement(time.Second)
+
+				var mountResponse dockerdriver.MountResponse
+				Eventually(responses).Should(Receive(&mountResponse))
+				Expect(mountResponse.Err).To(BeEmpty())
+				Expect(mountResponse.Mountpoint).To(Equal("somePath"))
+			})
+		})
+	})
+
+	Context("when the driver returns an error code", func() {
+		It("should pass the code on", func() {
+			httpClient.DoReturns(&http.Response{
+				StatusCode: driverhttp.StatusOK,
+				Body:       stringCloser{bytes.NewBufferString(`{"Err":"nope","ErrCode":"in-use"}`)},
+			}, nil)
+
+			unmountResponse := driver.Unmount(env, dockerdriver.UnmountRequest{Name: "fake-volume"})
+
+			Expect(unmountResponse.ErrCode).To(Equal(dockerdriver.ErrCodeInUse))
+			Expect(unmountResponse.DriverError()).To(MatchError(dockerdriver.ErrInUse))
+		})
+
+		It("should classify errors that come without a code", func() {
+			httpClient.DoReturns(&http.Response{
+				StatusCode: driverhttp.StatusOK,
+				Body:       stringCloser{bytes.NewBufferString(`{"Err":"Volume fake-volume does not exist"}`)},
+			}, nil)
+
+			getResponse := driver.Get(env, dockerdriver.GetRequest{Name: "fake-volume"})
+
+			Expect(getResponse.ErrCode).To(Equal(dockerdriver.ErrCodeNotFound))
+		})
+
+		It("should rebuild a SafeError the driver sent", func() {
+			httpClient.DoReturns(&http.Response{
+				StatusCode: driverhttp.StatusOK,
+				Body:       stringCloser{bytes.NewBufferString(`{"Err":"{\"SafeDescription\":\"share is not reachable\"}","ErrCode":"unavailable"}`)},
+			}, nil)
+
+			mountResponse := driver.Mount(env, dockerdriver.MountRequest{Name: "fake-volume"})
+
+			var safeError dockerdriver.SafeError
+			Expect(errors.As(mountResponse.DriverError(), &safeError)).To(BeTrue())
+			Expect(safeError.SafeDescription).To(Equal("share is not reachable"))
+			Expect(mountResponse.DriverError()).To(MatchError(dockerdriver.ErrUnavailable))
+		})
+
+		It("should mark a driver it cannot reach as unavailable", func() {
+			httpClient.DoReturns(nil, fmt.Errorf("some error"))
+
+			mountResponse := driver.Mount(env, dockerdriver.MountRequest{Name: "fake-volume"})
+
+			Expect(mountResponse.ErrCode).To(Equal(dockerdriver.ErrCodeUnavailable))
+			Expect(mountResponse.DriverError()).To(MatchError(dockerdriver.ErrUnavailable))
+		})
+	})
+
+	Context("when the driver does not respond within the route's timeout", func() {
+		BeforeEach(func() {
+			driver = driverhttp.NewRemoteClientWithClient("http://127.0.0.1:8080", nil, httpClient, fakeClock, driverhttp.WithTimeouts(driverhttp.Timeouts{
+				Default: time.Minute,
+				Routes: map[string]time.Duration{
+					dockerdriver.MountRoute: 50 * time.Millisecond,
+				},
+			}))
+
+			httpClient.DoStub = func(req *http.Request) (*http.Response, error) {
+				<-req.Context().Done()
+				return nil, req.Context().Err()
+			}
+		})
+
+		It("should return a timeout error", func() {
+			mountResponse := driver.Mount(env, dockerdriver.MountRequest{Name: "fake-volume"})
+
+			expected := &driverhttp.TimeoutError{Route: dockerdriver.MountRoute, Timeout: 50 * time.Millisecond}
+			Expect(mountResponse.Err).To(Equal(expected.Error()))
+			Expect(mountResponse.ErrCode).To(Equal(dockerdriver.ErrCodeTimeout))
+			Expect(expected).To(MatchError(dockerdriver.ErrTimeout))
+			Expect(expected).To(MatchError(driverhttp.ErrTimeout))
+		})
+
+		It("should leave the deadline alone when the caller's is earlier", func() {
+			deadlineCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+			defer cancel()
+
+			mountResponse := driver.Mount(driverhttp.NewHttpDriverEnv(testLogger, deadlineCtx), dockerdriver.MountRequest{Name: "fake-volume"})
+
+			Expect(mountResponse.Err).To(Equal(context.DeadlineExceeded.Error()))
+		})
+
+		It("should not retry a request that timed out", func() {
+			driver = driverhttp.NewRemoteClientWithClient("http://127.0.0.1:8080", nil, httpClient, fakeClock,
+				driverhttp.WithTimeouts(driverhttp.Timeouts{Default: 50 * time.Millisecond}),
+				driverhttp.WithRetryPolicy(driverhttp.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Second}),
+			)
+
+			pathResponse := driver.Path(env, dockerdriver.PathRequest{Name: "fake-volume"})
+
+			Expect(pathResponse.Err).To(ContainSubstring("timed out after 50ms"))
+			Expect(httpClient.DoCallCount()).To(Equal(1))
+		})
+	})
+
+	Context("when the client is created from a driver address", func() {
+		var (
+			fakeDriver *dockerdriverfakes.FakeDriver
+			server     *httptest.Server
+		)
+
+		BeforeEach(func() {
+			fakeDriver = &dockerdriverfakes.FakeDriver{}
+			fakeDriver.ActivateReturns(dockerdriver.ActivateResponse{Implements: []string{"VolumeDriver"}})
+
+			handler, err := driverhttp.NewHandler(testLogger, fakeDriver)
+			Expect(err).NotTo(HaveOccurred())
+			server = httptest.NewUnstartedServer(handler)
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		Context("when the address is a unix socket", func() {
+			var socketPath string
+
+			BeforeEach(func() {
+				tmpdir, err := os.MkdirTemp("", "rc")
+				Expect(err).NotTo(HaveOccurred())
+				DeferCleanup(os.RemoveAll, tmpdir)
+
+				socketPath = path.Join(tmpdir, "driver.sock")
+				server.Listener, err = net.Listen("unix", socketPath)
+				Expect(err).NotTo(HaveOccurred())
+				server.Start()
+			})
+
+			It("should dial the socket path", func() {
+				driver, err := driverhttp.NewRemoteClient(socketPath, nil)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(driver.Activate(env).Implements).To(Equal([]string{"VolumeDriver"}))
+			})
+
+			It("should dial a unix:// url", func() {
+				driver, err := driverhttp.NewRemoteClient("unix://"+socketPath, nil)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(driver.Activate(env).Implements).To(Equal([]string{"VolumeDriver"}))
+			})
+
+			It("should dial the address of a driver spec", func() {
+				driver, err := driverhttp.NewRemoteClientFromSpec(&dockerdriver.DriverSpec{Name: "some-driver", Address: socketPath})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(driver.Activate(env).Implements).To(Equal([]string{"VolumeDriver"}))
+			})
+
+			It("should still match the original address", func() {
+				driver, err := driverhttp.NewRemoteClient("unix://"+socketPath, nil)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(driver.Matches(testLogger, "unix://"+socketPath, nil)).To(BeTrue())
+			})
+		})
+
+		Context("when the address is tcp", func() {
+			var hostPort string
+
+			BeforeEach(func() {
+				server.Start()
+				hostPort = strings.TrimPrefix(server.URL, "http://")
+			})
+
+			It("should map tcp:// to http", func() {
+				driver, err := driverhttp.NewRemoteClient("tcp://"+hostPort, nil)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(driver.Activate(env).Implements).To(Equal([]string{"VolumeDriver"}))
+			})
+
+			It("should accept a bare host and port", func() {
+				driver, err := driverhttp.NewRemoteClient(hostPort, nil)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(driver.Activate(env).Implements).To(Equal([]string{"VolumeDriver"}))
+			})
+
+			It("should accept an http url", func() {
+				driver, err := driverhttp.NewRemoteClient(server.URL, nil)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(driver.Activate(env).Implements).To(Equal([]string{"VolumeDriver"}))
+			})
+		})
+
+		It("should reject an empty address", func() {
+			_, err := driverhttp.NewRemoteClient("", nil)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should reject an unsupported scheme", func() {
+			_, err := driverhttp.NewRemoteClient("npipe:////./pipe/some-driver", nil)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should reject a nil driver spec", func() {
+			_, err := driverhttp.NewRemoteClientFromSpec(nil)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
 	Context("when the transport is unix", func() {
 		var (
 			volumeId                     string
@@ -312,7 +759,7 @@ var _ = Describe("RemoteClient", func() {
 					"-listenAddr", socketPath,
 					"-transport", "unix",
 				),
-				StartCheck: "localdriver-server.started",
+				StartCheck: "driver-server.started",
 			})
 
 			httpClient = new(http_fake.FakeClient)
@@ -0,0 +1,110 @@
+package driverhttp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	cf_http_handlers "code.cloudfoundry.org/cfhttp/v2/handlers"
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/drivertracing"
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/google/uuid"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength stops a caller filling the driver's logs through the
+// request id; longer ids are replaced.
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	requestID, ok := ctx.Value(requestIDKey{}).(string)
+	return requestID, ok && requestID != ""
+}
+
+// ensureRequestID returns ctx with a request id, generating one if ctx has
+// none.
+func ensureRequestID(ctx context.Context) (context.Context, string) {
+	if requestID, ok := RequestIDFromContext(ctx); ok {
+		return ctx, requestID
+	}
+	requestID := uuid.NewString()
+	return ContextWithRequestID(ctx, requestID), requestID
+}
+
+// envWithRequestID gives env a request id, if it has none, and ties its logs
+// to it.
+func envWithRequestID(env dockerdriver.Env) dockerdriver.Env {
+	ctx, _ := ensureRequestID(env.Context())
+	return &voldriverEnv{env.Logger().WithData(requestIDLogData(ctx)), ctx}
+}
+
+func requestIDLogData(ctx context.Context) lager.Data {
+	requestID, ok := RequestIDFromContext(ctx)
+	if !ok {
+		return lager.Data{}
+	}
+	return lager.Data{"request-id": requestID}
+}
+
+// requestLogData is the session data handlers tie their logs to the request
+// with: its request id and any trace it is part of.
+func requestLogData(req *http.Request) lager.Data {
+	data := drivertracing.LogData(req.Context())
+	for key, value := range requestIDLogData(req.Context()) {
+		data[key] = value
+	}
+	return data
+}
+
+// identifyRequests takes the request id from the caller's header, or makes
+// one up, puts it in the request context and echoes it in the response.
+func identifyRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		if requestID := req.Header.Get(RequestIDHeader); isValidRequestID(requestID) {
+			ctx = ContextWithRequestID(ctx, requestID)
+		}
+		ctx, requestID := ensureRequestID(ctx)
+
+		w.Header().Set(RequestIDHeader, requestID)
+		next.ServeHTTP(w, req.WithContext(ctx))
+	})
+}
+
+func isValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range requestID {
+		if c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// writeErrorResponse writes a driver error response with the request id
+// added to the body as RequestID, which docker ignores.
+func writeErrorResponse(w http.ResponseWriter, req *http.Request, response interface{}) {
+	requestID, ok := RequestIDFromContext(req.Context())
+	if !ok {
+		cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, response)
+		return
+	}
+
+	var body map[string]json.RawMessage
+	if encoded, err := json.Marshal(response); err == nil && json.Unmarshal(encoded, &body) == nil {
+		body["RequestID"], _ = json.Marshal(requestID)
+		cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, body)
+		return
+	}
+	cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, response)
+}
@@ -0,0 +1,143 @@
+package driverhttp_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/dockerdriverfakes"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func requestIDOf(ctx context.Context) string {
+	requestID, _ := driverhttp.RequestIDFromContext(ctx)
+	return requestID
+}
+
+var _ = Describe("Request IDs", func() {
+	var (
+		testLogger    *lagertest.TestLogger
+		fakeDriver    *dockerdriverfakes.FakeDriver
+		handler       http.Handler
+		driverContext context.Context
+	)
+
+	BeforeEach(func() {
+		testLogger = lagertest.NewTestLogger("request-id-test")
+
+		fakeDriver = &dockerdriverfakes.FakeDriver{}
+		fakeDriver.MountStub = func(env dockerdriver.Env, _ dockerdriver.MountRequest) dockerdriver.MountResponse {
+			driverContext = env.Context()
+			env.Logger().Info("mounting")
+			return dockerdriver.MountResponse{Mountpoint: "/some/path"}
+		}
+
+		var err error
+		handler, err = driverhttp.NewHandler(testLogger, fakeDriver)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	mount := func(requestID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/VolumeDriver.Mount", strings.NewReader(`{"Name":"some-volume"}`))
+		if requestID != "" {
+			req.Header.Set(driverhttp.RequestIDHeader, requestID)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	Context("when handling a request", func() {
+		It("should give the driver the caller's request id and echo it back", func() {
+			recorder := mount("some-request-id")
+
+			Expect(recorder.Header().Get(driverhttp.RequestIDHeader)).To(Equal("some-request-id"))
+
+			requestID, ok := driverhttp.RequestIDFromContext(driverContext)
+			Expect(ok).To(BeTrue())
+			Expect(requestID).To(Equal("some-request-id"))
+
+			Expect(testLogger.Logs()).To(ContainElement(SatisfyAll(
+				HaveField("Message", HaveSuffix("mounting")),
+				HaveField("Data", HaveKeyWithValue("request-id", "some-request-id")),
+			)))
+		})
+
+		It("should make up a request id when the caller sends none", func() {
+			recorder := mount("")
+
+			requestID := recorder.Header().Get(driverhttp.RequestIDHeader)
+			Expect(requestID).NotTo(BeEmpty())
+			Expect(requestIDOf(driverContext)).To(Equal(requestID))
+		})
+
+		It("should replace a request id that isn't fit to log", func() {
+			recorder := mount("some\nrequest-id")
+
+			Expect(recorder.Header().Get(driverhttp.RequestIDHeader)).NotTo(ContainSubstring("some"))
+		})
+
+		It("should include the request id in error bodies", func() {
+			fakeDriver.MountStub = nil
+			fakeDriver.MountReturns(dockerdriver.MountResponse{Err: "badness", ErrCode: dockerdriver.ErrCodeInUse})
+
+			recorder := mount("some-request-id")
+
+			var body map[string]string
+			Expect(json.Unmarshal(recorder.Body.Bytes(), &body)).To(Succeed())
+			Expect(body).To(HaveKeyWithValue("RequestID", "some-request-id"))
+			Expect(body).To(HaveKeyWithValue("Err", "badness"))
+			Expect(body).To(HaveKeyWithValue("ErrCode", "in-use"))
+		})
+	})
+
+	Context("when calling a remote driver", func() {
+		var (
+			requestIDs []string
+			client     dockerdriver.Driver
+		)
+
+		BeforeEach(func() {
+			requestIDs = nil
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				requestIDs = append(requestIDs, req.Header.Get(driverhttp.RequestIDHeader))
+				handler.ServeHTTP(w, req)
+			}))
+			DeferCleanup(server.Close)
+
+			var err error
+			client, err = driverhttp.NewRemoteClient(server.URL, nil)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should send the Env's request id", func() {
+			ctx := driverhttp.ContextWithRequestID(context.Background(), "some-request-id")
+			client.Mount(driverhttp.NewHttpDriverEnv(testLogger, ctx), dockerdriver.MountRequest{Name: "some-volume"})
+
+			Expect(requestIDs).To(Equal([]string{"some-request-id"}))
+			Expect(requestIDOf(driverContext)).To(Equal("some-request-id"))
+		})
+
+		It("should send a fresh request id for each call when the Env has none", func() {
+			env := driverhttp.NewHttpDriverEnv(testLogger, context.Background())
+			client.Mount(env, dockerdriver.MountRequest{Name: "some-volume"})
+			client.Mount(env, dockerdriver.MountRequest{Name: "some-volume"})
+
+			Expect(requestIDs).To(HaveLen(2))
+			Expect(requestIDs[0]).NotTo(BeEmpty())
+			Expect(requestIDs[1]).NotTo(BeEmpty())
+			Expect(requestIDs[0]).NotTo(Equal(requestIDs[1]))
+
+			Expect(testLogger.Logs()).To(ContainElement(SatisfyAll(
+				HaveField("Message", HaveSuffix("remoteclient-mount.start")),
+				HaveField("Data", HaveKeyWithValue("request-id", requestIDs[0])),
+			)))
+		})
+	})
+})
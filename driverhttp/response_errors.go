@@ -0,0 +1,115 @@
+package driverhttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"code.cloudfoundry.org/dockerdriver"
+)
+
+// maxErrorBodyBytes caps how much of a body the client reads when it is only
+// going to report it, so a misbehaving server can't make it buffer a page.
+const maxErrorBodyBytes = 4 * 1024
+
+// StatusError is returned when the driver, or a proxy in front of it, answers
+// with a non-2xx status. Some docker plugins still send a docker style
+// {"Err": ...} body with their error statuses; that error is kept as Driver,
+// and its message is used as is.
+type StatusError struct {
+	StatusCode int
+	Status     string
+	Body       string
+	Driver     *dockerdriver.DriverError
+}
+
+func (e *StatusError) Error() string {
+	if e.Driver != nil {
+		return e.Driver.Error()
+	}
+	return fmt.Sprintf("driver responded with %s: %q", e.Status, e.Body)
+}
+
+func (e *StatusError) Unwrap() error {
+	if e.Driver == nil {
+		return nil
+	}
+	return e.Driver
+}
+
+func (e *StatusError) code() dockerdriver.ErrorCode {
+	switch e.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		return dockerdriver.ErrCodeUnavailable
+	case http.StatusGatewayTimeout:
+		return dockerdriver.ErrCodeTimeout
+	default:
+		return ""
+	}
+}
+
+// ContentError is returned when a response body is not JSON, e.g. an html
+// error page.
+type ContentError struct {
+	StatusCode  int
+	ContentType string
+	Body        string
+}
+
+func (e *ContentError) Error() string {
+	return fmt.Sprintf("driver responded with %d and non-json content of type %q: %q", e.StatusCode, e.ContentType, e.Body)
+}
+
+func newStatusError(response *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(response.Body, maxErrorBodyBytes))
+
+	statusErr := &StatusError{
+		StatusCode: response.StatusCode,
+		Status:     response.Status,
+		Body:       string(body),
+	}
+	if statusErr.Status == "" {
+		statusErr.Status = fmt.Sprintf("%d %s", response.StatusCode, http.StatusText(response.StatusCode))
+	}
+
+	var remoteErrorResponse dockerdriver.ErrorResponse
+	if json.Unmarshal(body, &remoteErrorResponse) == nil && remoteErrorResponse.Err != "" {
+		statusErr.Driver = newDriverError(remoteErrorResponse)
+	}
+	return statusErr
+}
+
+func newContentError(response *http.Response, body []byte) error {
+	if len(body) > maxErrorBodyBytes {
+		body = body[:maxErrorBodyBytes]
+	}
+	return &ContentError{
+		StatusCode:  response.StatusCode,
+		ContentType: response.Header.Get("Content-Type"),
+		Body:        string(body),
+	}
+}
+
+// isJSONContentType allows a missing content type and text/plain, which is
+// what go's http server sniffs for a JSON body written without one.
+func isJSONContentType(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") || mediaType == "text/plain"
+}
+
+func newDriverError(remoteErrorResponse dockerdriver.ErrorResponse) *dockerdriver.DriverError {
+	code := remoteErrorResponse.ErrCode
+	if code == "" {
+		code = dockerdriver.ClassifyError(remoteErrorResponse.Err)
+	}
+	return dockerdriver.NewDriverError(code, remoteErrorResponse.Err)
+}
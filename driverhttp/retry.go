@@ -0,0 +1,105 @@
+package driverhttp
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"time"
+
+	"code.cloudfoundry.org/dockerdriver"
+)
+
+// RetryPolicy controls how remoteClient retries requests that fail to reach
+// the driver at all, e.g. while the driver is restarting. Errors reported by
+// the driver are never retried. Mount and Unmount are only retried when
+// opted in, as a driver may have acted on a request whose response was lost.
+type RetryPolicy struct {
+	// MaxAttempts includes the first attempt; zero or one disables retries.
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	// Jitter randomizes each backoff by up to this fraction either way.
+	Jitter float64
+
+	RetryMount   bool
+	RetryUnmount bool
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+}
+
+func WithRetryPolicy(policy RetryPolicy) RemoteClientOption {
+	return func(r *remoteClient) {
+		r.retryPolicy = policy
+	}
+}
+
+func (p RetryPolicy) attemptsFor(route string) int {
+	if p.MaxAttempts <= 1 {
+		return 1
+	}
+
+	switch route {
+	case dockerdriver.GetRoute, dockerdriver.ListRoute, dockerdriver.PathRoute, dockerdriver.CapabilitiesRoute, dockerdriver.ActivateRoute:
+		return p.MaxAttempts
+	case dockerdriver.MountRoute:
+		if p.RetryMount {
+			return p.MaxAttempts
+		}
+	case dockerdriver.UnmountRoute:
+		if p.RetryUnmount {
+			return p.MaxAttempts
+		}
+	}
+	return 1
+}
+
+// backoff returns the wait after the given (1-based) failed attempt.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	backoff := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		backoff += backoff * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(backoff)
+}
+
+// connectionError marks a failure to get any response from the driver.
+type connectionError struct {
+	err error
+}
+
+func (e *connectionError) Error() string {
+	return e.err.Error()
+}
+
+func (e *connectionError) Unwrap() error {
+	return e.err
+}
+
+func (e *connectionError) Is(target error) bool {
+	return target == dockerdriver.ErrUnavailable
+}
+
+// isConnectionError is false once the caller's context is done: there is no
+// point retrying a request nobody is waiting for.
+func isConnectionError(ctx context.Context, err error) bool {
+	var connErr *connectionError
+	return errors.As(err, &connErr) && ctx.Err() == nil
+}
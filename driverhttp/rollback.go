@@ -0,0 +1,37 @@
+package driverhttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/lager/v3"
+)
+
+// mountRollbackTimeout bounds the unmount of an abandoned mount, which can no
+// longer use the request's deadline.
+const mountRollbackTimeout = 2 * time.Minute
+
+// WithMountRollback unmounts volumes whose mount succeeded only after the
+// caller had gone away, since the caller will never unmount them itself.
+func WithMountRollback() HandlerOption {
+	return func(c *handlerConfig) {
+		c.rollbackMounts = true
+	}
+}
+
+func rollbackMount(logger lager.Logger, req *http.Request, client dockerdriver.Driver, mountRequest dockerdriver.MountRequest) {
+	logger = logger.Session("rollback-mount", lager.Data{"volume": mountRequest.Name, "id": mountRequest.ID})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(req.Context()), mountRollbackTimeout)
+	defer cancel()
+
+	unmountResponse := client.Unmount(NewHttpDriverEnv(logger, ctx), dockerdriver.UnmountRequest{Name: mountRequest.Name, ID: mountRequest.ID})
+	if unmountResponse.Err != "" {
+		logger.Error("failed-rolling-back-mount", errors.New(unmountResponse.Err))
+	}
+}
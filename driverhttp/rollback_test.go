@@ -0,0 +1,140 @@
+package driverhttp_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/dockerdriverfakes"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Mount rollback", func() {
+	var (
+		testLogger *lagertest.TestLogger
+		driver     *dockerdriverfakes.FakeDriver
+		opts       []driverhttp.HandlerOption
+		res        *httptest.ResponseRecorder
+		req        *http.Request
+		cancel     context.CancelFunc
+	)
+
+	BeforeEach(func() {
+		testLogger = lagertest.NewTestLogger("rollback-test")
+		driver = &dockerdriverfakes.FakeDriver{}
+		opts = []driverhttp.HandlerOption{driverhttp.WithMountRollback()}
+
+		var ctx context.Context
+		ctx, cancel = context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+
+		res = httptest.NewRecorder()
+		req = httptest.NewRequest("POST", "/VolumeDriver.Mount", strings.NewReader(`{"Name":"some-volume","ID":"some-id"}`)).WithContext(ctx)
+		req.Header.Set(driverhttp.RequestIDHeader, "some-request-id")
+	})
+
+	JustBeforeEach(func() {
+		subject, err := driverhttp.NewHandler(testLogger, driver, opts...)
+		Expect(err).NotTo(HaveOccurred())
+
+		subject.ServeHTTP(res, req)
+	})
+
+	mountResponse := func() dockerdriver.MountResponse {
+		var response dockerdriver.MountResponse
+		Expect(json.Unmarshal(res.Body.Bytes(), &response)).To(Succeed())
+		return response
+	}
+
+	Context("when the caller goes away while the volume is mounted", func() {
+		var (
+			unmountContext    context.Context
+			unmountContextErr error
+		)
+
+		BeforeEach(func() {
+			driver.MountStub = func(dockerdriver.Env, dockerdriver.MountRequest) dockerdriver.MountResponse {
+				cancel()
+				return dockerdriver.MountResponse{Mountpoint: "/some/path"}
+			}
+			driver.UnmountStub = func(env dockerdriver.Env, _ dockerdriver.UnmountRequest) dockerdriver.ErrorResponse {
+				unmountContext = env.Context()
+				unmountContextErr = unmountContext.Err()
+				return dockerdriver.ErrorResponse{}
+			}
+		})
+
+		It("should unmount the volume", func() {
+			Expect(driver.UnmountCallCount()).To(Equal(1))
+			_, unmountRequest := driver.UnmountArgsForCall(0)
+			Expect(unmountRequest).To(Equal(dockerdriver.UnmountRequest{Name: "some-volume", ID: "some-id"}))
+		})
+
+		It("should unmount with a live context that keeps the request id", func() {
+			Expect(unmountContextErr).NotTo(HaveOccurred())
+			_, hasDeadline := unmountContext.Deadline()
+			Expect(hasDeadline).To(BeTrue())
+			Expect(requestIDOf(unmountContext)).To(Equal("some-request-id"))
+		})
+
+		It("should answer with an error rather than the mountpoint", func() {
+			Expect(mountResponse().Err).To(ContainSubstring("rolled back"))
+			Expect(mountResponse().Mountpoint).To(BeEmpty())
+		})
+
+		Context("when unmounting fails", func() {
+			BeforeEach(func() {
+				driver.UnmountStub = nil
+				driver.UnmountReturns(dockerdriver.ErrorResponse{Err: "device busy"})
+			})
+
+			It("should log the failure", func() {
+				Expect(testLogger.Logs()).To(ContainElement(SatisfyAll(
+					HaveField("Message", HaveSuffix("failed-rolling-back-mount")),
+					HaveField("Data", HaveKeyWithValue("volume", "some-volume")),
+				)))
+			})
+		})
+
+		Context("when rollback is not enabled", func() {
+			BeforeEach(func() {
+				opts = nil
+			})
+
+			It("should leave the volume mounted", func() {
+				Expect(driver.UnmountCallCount()).To(Equal(0))
+				Expect(mountResponse().Mountpoint).To(Equal("/some/path"))
+			})
+		})
+	})
+
+	Context("when the mount fails after the caller goes away", func() {
+		BeforeEach(func() {
+			driver.MountStub = func(dockerdriver.Env, dockerdriver.MountRequest) dockerdriver.MountResponse {
+				cancel()
+				return dockerdriver.MountResponse{Err: "context canceled"}
+			}
+		})
+
+		It("should have nothing to roll back", func() {
+			Expect(driver.UnmountCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when the caller waits for the mount", func() {
+		BeforeEach(func() {
+			driver.MountReturns(dockerdriver.MountResponse{Mountpoint: "/some/path"})
+		})
+
+		It("should return the mountpoint", func() {
+			Expect(driver.UnmountCallCount()).To(Equal(0))
+			Expect(mountResponse().Mountpoint).To(Equal("/some/path"))
+		})
+	})
+})
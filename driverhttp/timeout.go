@@ -0,0 +1,85 @@
+package driverhttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"code.cloudfoundry.org/dockerdriver"
+)
+
+var ErrTimeout = errors.New("timed out waiting for driver")
+
+// TimeoutError is returned when a request outlives its route's timeout, as
+// opposed to failing with an error reported by the driver.
+type TimeoutError struct {
+	Route   string
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("timed out after %s waiting for driver to respond to %s", e.Timeout, e.Route)
+}
+
+func (e *TimeoutError) Is(target error) bool {
+	return target == ErrTimeout || target == dockerdriver.ErrTimeout
+}
+
+// Timeouts bound how long remoteClient waits on each route. They only apply
+// when the caller's context has no earlier deadline of its own.
+type Timeouts struct {
+	// Default applies to routes missing from Routes; zero means no timeout.
+	Default time.Duration
+	Routes  map[string]time.Duration
+}
+
+func DefaultTimeouts() Timeouts {
+	return Timeouts{
+		Default: time.Minute,
+		Routes: map[string]time.Duration{
+			dockerdriver.ActivateRoute:     30 * time.Second,
+			dockerdriver.CapabilitiesRoute: 10 * time.Second,
+			dockerdriver.GetRoute:          30 * time.Second,
+			dockerdriver.PathRoute:         30 * time.Second,
+			dockerdriver.CreateRoute:       2 * time.Minute,
+			dockerdriver.RemoveRoute:       2 * time.Minute,
+			dockerdriver.MountRoute:        5 * time.Minute,
+			dockerdriver.UnmountRoute:      2 * time.Minute,
+		},
+	}
+}
+
+func WithTimeouts(timeouts Timeouts) RemoteClientOption {
+	return func(r *remoteClient) {
+		r.timeouts = timeouts
+	}
+}
+
+func (t Timeouts) forRoute(route string) time.Duration {
+	if timeout, ok := t.Routes[route]; ok {
+		return timeout
+	}
+	return t.Default
+}
+
+func (t Timeouts) withTimeout(ctx context.Context, route string) (context.Context, context.CancelFunc) {
+	timeout := t.forRoute(route)
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= timeout {
+		return ctx, func() {}
+	}
+	return context.WithTimeoutCause(ctx, timeout, &TimeoutError{Route: route, Timeout: timeout})
+}
+
+// timeoutError returns the TimeoutError for a context from withTimeout that
+// timed out, or nil if it did not.
+func timeoutError(ctx context.Context) error {
+	var timeoutErr *TimeoutError
+	if errors.As(context.Cause(ctx), &timeoutErr) {
+		return timeoutErr
+	}
+	return nil
+}
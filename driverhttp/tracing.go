@@ -0,0 +1,42 @@
+package driverhttp
+
+import (
+	"net/http"
+
+	"code.cloudfoundry.org/dockerdriver/drivertracing"
+)
+
+// WithClientTracing sets the headers the client sends the span in the Env's
+// context in; no formats keeps the default, W3C traceparent. With a tracer,
+// each request also gets a client span of its own.
+func WithClientTracing(tracer *drivertracing.Tracer, formats drivertracing.Format) RemoteClientOption {
+	return func(r *remoteClient) {
+		r.tracer = tracer
+		if formats != 0 {
+			r.traceFormat = formats
+		}
+	}
+}
+
+// WithHandlerTracing starts a server span for each request, as a child of
+// the span the caller sent. Without it the caller's span is still passed on
+// to the driver in the Env's context.
+func WithHandlerTracing(tracer *drivertracing.Tracer) HandlerOption {
+	return func(c *handlerConfig) {
+		c.tracer = tracer
+	}
+}
+
+func traceRequests(tracer *drivertracing.Tracer, route string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx := drivertracing.ContextWithRemoteSpanContext(req.Context(), req.Header)
+		if tracer != nil {
+			var span *drivertracing.Span
+			ctx, span = tracer.Start(ctx, "handle-"+route, drivertracing.SpanKindServer)
+			span.SetAttribute("route", route)
+			defer span.End()
+		}
+
+		next.ServeHTTP(w, req.WithContext(ctx))
+	})
+}
@@ -0,0 +1,118 @@
+package driverhttp_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/dockerdriverfakes"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/dockerdriver/drivertracing"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tracing", func() {
+	var (
+		testLogger     *lagertest.TestLogger
+		fakeDriver     *dockerdriverfakes.FakeDriver
+		clientExporter *drivertracing.InMemoryExporter
+		serverExporter *drivertracing.InMemoryExporter
+		server         *httptest.Server
+		headers        http.Header
+		driverContext  context.Context
+		callerCtx      context.Context
+		callerSpan     *drivertracing.Span
+	)
+
+	BeforeEach(func() {
+		testLogger = lagertest.NewTestLogger("tracing-test")
+		clientExporter = drivertracing.NewInMemoryExporter()
+		serverExporter = drivertracing.NewInMemoryExporter()
+
+		fakeDriver = &dockerdriverfakes.FakeDriver{}
+		fakeDriver.MountStub = func(env dockerdriver.Env, _ dockerdriver.MountRequest) dockerdriver.MountResponse {
+			driverContext = env.Context()
+			env.Logger().Info("mounting")
+			return dockerdriver.MountResponse{Mountpoint: "/some/path"}
+		}
+
+		handler, err := driverhttp.NewHandler(testLogger, fakeDriver, driverhttp.WithHandlerTracing(drivertracing.NewTracer(serverExporter, clock.NewClock())))
+		Expect(err).NotTo(HaveOccurred())
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			headers = req.Header.Clone()
+			handler.ServeHTTP(w, req)
+		}))
+		DeferCleanup(server.Close)
+
+		callerCtx, callerSpan = drivertracing.NewTracer(nil, clock.NewClock()).Start(context.Background(), "start-app", drivertracing.SpanKindServer)
+	})
+
+	It("should link the driver's work to the caller's span", func() {
+		client, err := driverhttp.NewRemoteClient(server.URL, nil, driverhttp.WithClientTracing(drivertracing.NewTracer(clientExporter, clock.NewClock()), drivertracing.FormatW3C))
+		Expect(err).NotTo(HaveOccurred())
+
+		response := client.Mount(driverhttp.NewHttpDriverEnv(testLogger, callerCtx), dockerdriver.MountRequest{Name: "some-volume"})
+		Expect(response.Err).To(BeEmpty())
+
+		traceID := callerSpan.SpanContext().TraceID
+
+		Expect(clientExporter.Spans()).To(HaveLen(1))
+		clientSpan := clientExporter.Spans()[0]
+		Expect(clientSpan.Name).To(Equal("remoteclient-mount"))
+		Expect(clientSpan.SpanContext.TraceID).To(Equal(traceID))
+		Expect(clientSpan.ParentSpanID).To(Equal(callerSpan.SpanContext().SpanID))
+		Expect(headers.Get("traceparent")).To(ContainSubstring(clientSpan.SpanContext.SpanID.String()))
+
+		Expect(serverExporter.Spans()).To(HaveLen(1))
+		serverSpan := serverExporter.Spans()[0]
+		Expect(serverSpan.Name).To(Equal("handle-mount"))
+		Expect(serverSpan.SpanContext.TraceID).To(Equal(traceID))
+		Expect(serverSpan.ParentSpanID).To(Equal(clientSpan.SpanContext.SpanID))
+
+		driverSpan, ok := drivertracing.SpanContextFromContext(driverContext)
+		Expect(ok).To(BeTrue())
+		Expect(driverSpan).To(Equal(serverSpan.SpanContext))
+
+		Expect(testLogger.Logs()).To(ContainElement(SatisfyAll(
+			HaveField("Message", HaveSuffix("mounting")),
+			HaveField("Data", HaveKeyWithValue("trace-id", traceID.String())),
+		)))
+	})
+
+	It("should send the caller's span in B3 headers when asked to", func() {
+		client, err := driverhttp.NewRemoteClient(server.URL, nil, driverhttp.WithClientTracing(nil, drivertracing.FormatB3))
+		Expect(err).NotTo(HaveOccurred())
+
+		client.Mount(driverhttp.NewHttpDriverEnv(testLogger, callerCtx), dockerdriver.MountRequest{Name: "some-volume"})
+
+		Expect(headers.Get("traceparent")).To(BeEmpty())
+		Expect(headers.Get("X-B3-TraceId")).To(Equal(callerSpan.SpanContext().TraceID.String()))
+		Expect(headers.Get("X-B3-SpanId")).To(Equal(callerSpan.SpanContext().SpanID.String()))
+	})
+
+	It("should keep sending traceparent when no formats are given", func() {
+		client, err := driverhttp.NewRemoteClient(server.URL, nil, driverhttp.WithClientTracing(drivertracing.NewTracer(clientExporter, clock.NewClock()), 0))
+		Expect(err).NotTo(HaveOccurred())
+
+		client.Mount(driverhttp.NewHttpDriverEnv(testLogger, callerCtx), dockerdriver.MountRequest{Name: "some-volume"})
+
+		Expect(headers.Get("traceparent")).To(ContainSubstring(callerSpan.SpanContext().TraceID.String()))
+		Expect(headers.Get("X-B3-TraceId")).To(BeEmpty())
+	})
+
+	It("should send nothing when the caller has no span", func() {
+		client, err := driverhttp.NewRemoteClient(server.URL, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		client.Mount(driverhttp.NewHttpDriverEnv(testLogger, context.Background()), dockerdriver.MountRequest{Name: "some-volume"})
+
+		Expect(headers.Get("traceparent")).To(BeEmpty())
+		Expect(serverExporter.Spans()).To(HaveLen(1))
+		Expect(serverExporter.Spans()[0].ParentSpanID.IsValid()).To(BeFalse())
+	})
+})
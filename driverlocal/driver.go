@@ -0,0 +1,413 @@
+// Package driverlocal is a reference Driver that backs each volume with a
+// directory on the local disk. It needs no storage server and, unless told to
+// bind mount, no root, so it can stand in for a real driver in development and
+// in the integration suite.
+package driverlocal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverreconcile"
+	"code.cloudfoundry.org/dockerdriver/driverstate"
+	"code.cloudfoundry.org/lager/v3"
+)
+
+type Config struct {
+	// RootDir holds each volume's data in RootDir/volumes/<name>, and the
+	// driver's state.
+	RootDir string
+	// MountDir is where mountpoints are made, as MountDir/<name>. It
+	// defaults to RootDir/mounts.
+	MountDir string
+	// BindMounts bind mounts each volume's directory at its mountpoint,
+	// which needs root. Without it mountpoints are symlinks.
+	BindMounts bool
+}
+
+// LocalDriver is safe to use from many goroutines. Its operations are
+// serialized, which is plenty for the directories it manages.
+type LocalDriver struct {
+	config Config
+	store  driverstate.VolumeStore
+	lock   sync.Mutex
+}
+
+func NewLocalDriver(logger lager.Logger, config Config) (*LocalDriver, error) {
+	logger = logger.Session("new-local-driver", lager.Data{"config": config})
+
+	if config.RootDir == "" {
+		return nil, errors.New("root directory is required")
+	}
+	if config.MountDir == "" {
+		config.MountDir = filepath.Join(config.RootDir, "mounts")
+	}
+
+	for _, dir := range []string{filepath.Join(config.RootDir, "volumes"), config.MountDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			logger.Error("failed-creating-directory", err)
+			return nil, err
+		}
+	}
+
+	store, err := driverstate.NewFileStore(logger, filepath.Join(config.RootDir, "volumes.json"))
+	if err != nil {
+		logger.Error("failed-loading-state", err)
+		return nil, err
+	}
+
+	return &LocalDriver{config: config, store: store}, nil
+}
+
+func (d *LocalDriver) dataDir(name string) string {
+	return filepath.Join(d.config.RootDir, "volumes", name)
+}
+
+// tombstone is where a volume's data goes while it is removed. Volume names
+// can't start with a dot, so it can't be another volume's directory.
+func (d *LocalDriver) tombstone(name string) string {
+	return filepath.Join(d.config.RootDir, "volumes", "."+name+".removed")
+}
+
+func (d *LocalDriver) mountpoint(name string) string {
+	return filepath.Join(d.config.MountDir, name)
+}
+
+func (d *LocalDriver) Activate(env dockerdriver.Env) dockerdriver.ActivateResponse {
+	return dockerdriver.ActivateResponse{Implements: []string{"VolumeDriver"}}
+}
+
+func (d *LocalDriver) Capabilities(env dockerdriver.Env) dockerdriver.CapabilitiesResponse {
+	return dockerdriver.CapabilitiesResponse{Capabilities: dockerdriver.CapabilityInfo{Scope: "local"}}
+}
+
+func (d *LocalDriver) Create(env dockerdriver.Env, createRequest dockerdriver.CreateRequest) dockerdriver.ErrorResponse {
+	logger := env.Logger().Session("create", lager.Data{"volume": createRequest.Name})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	if err := d.create(createRequest); err != nil {
+		logger.Error("failed-creating-volume", err)
+		return dockerdriver.ErrorResponse{Err: err.Error(), ErrCode: dockerdriver.ErrorCodeOf(err)}
+	}
+	return dockerdriver.ErrorResponse{}
+}
+
+// create succeeds without changing anything if the volume exists, as
+// docker's own local driver does.
+func (d *LocalDriver) create(createRequest dockerdriver.CreateRequest) error {
+	if err := validateName(createRequest.Name); err != nil {
+		return err
+	}
+	options, err := parseOptions(createRequest.Opts)
+	if err != nil {
+		return err
+	}
+	if options.readonly && !d.config.BindMounts {
+		return invalidOptions("readonly needs the driver to bind mount volumes")
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if _, err := d.store.Get(createRequest.Name); err == nil {
+		return nil
+	}
+
+	dataDir := d.dataDir(createRequest.Name)
+	if err := os.Mkdir(dataDir, options.mode); err != nil && !os.IsExist(err) {
+		return err
+	}
+	if err := d.prepareDataDir(dataDir, options); err != nil {
+		os.RemoveAll(dataDir)
+		return err
+	}
+
+	record := driverstate.NewVolumeRecord(createRequest)
+	record.Mountpoint = d.mountpoint(createRequest.Name)
+	if err := d.store.Create(record); err != nil {
+		os.RemoveAll(dataDir)
+		return err
+	}
+	return nil
+}
+
+func (d *LocalDriver) prepareDataDir(dataDir string, options volumeOptions) error {
+	// Mkdir's mode is cut down by the umask
+	if err := os.Chmod(dataDir, options.mode); err != nil {
+		return err
+	}
+	if options.uid != -1 || options.gid != -1 {
+		return os.Chown(dataDir, options.uid, options.gid)
+	}
+	return nil
+}
+
+func (d *LocalDriver) Remove(env dockerdriver.Env, removeRequest dockerdriver.RemoveRequest) dockerdriver.ErrorResponse {
+	logger := env.Logger().Session("remove", lager.Data{"volume": removeRequest.Name})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	if err := d.remove(removeRequest.Name); err != nil {
+		logger.Error("failed-removing-volume", err)
+		return dockerdriver.ErrorResponse{Err: err.Error(), ErrCode: dockerdriver.ErrorCodeOf(err)}
+	}
+	return dockerdriver.ErrorResponse{}
+}
+
+// remove succeeds if the volume doesn't exist, so it can be retried.
+func (d *LocalDriver) remove(name string) error {
+	if err := validateName(name); err != nil {
+		return err
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	tombstone := d.tombstone(name)
+
+	record, err := d.store.Get(name)
+	if errors.Is(err, dockerdriver.ErrNotFound) {
+		// finish off a remove that deleted the record but not the data
+		return os.RemoveAll(tombstone)
+	}
+	if err != nil {
+		return err
+	}
+	if record.MountCount > 0 {
+		return dockerdriver.NewDriverError(dockerdriver.ErrCodeInUse, fmt.Sprintf("Volume %s is still mounted %d times", name, record.MountCount))
+	}
+
+	// the data is only deleted once the record is, so a failed remove leaves
+	// a volume that can still be served
+	if err := os.RemoveAll(tombstone); err != nil {
+		return err
+	}
+	if err := os.Rename(d.dataDir(name), tombstone); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := d.store.Delete(name); err != nil {
+		os.Rename(tombstone, d.dataDir(name))
+		return err
+	}
+	return os.RemoveAll(tombstone)
+}
+
+func (d *LocalDriver) Mount(env dockerdriver.Env, mountRequest dockerdriver.MountRequest) dockerdriver.MountResponse {
+	logger := env.Logger().Session("mount", lager.Data{"volume": mountRequest.Name, "id": mountRequest.ID})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	mountpoint, err := d.mount(mountRequest.Name)
+	if err != nil {
+		logger.Error("failed-mounting-volume", err)
+		return dockerdriver.MountResponse{Err: err.Error(), ErrCode: dockerdriver.ErrorCodeOf(err)}
+	}
+	return dockerdriver.MountResponse{Mountpoint: mountpoint}
+}
+
+// mount only makes the mountpoint for the first mount; later ones share it.
+func (d *LocalDriver) mount(name string) (string, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	record, err := d.get(name)
+	if err != nil {
+		return "", err
+	}
+
+	if record.MountCount == 0 {
+		if err := d.attach(record); err != nil {
+			return "", err
+		}
+	}
+
+	err = d.store.Update(name, func(record *driverstate.VolumeRecord) error {
+		record.MountCount++
+		return nil
+	})
+	if err != nil {
+		if record.MountCount == 0 {
+			d.detach(record.Mountpoint)
+		}
+		return "", err
+	}
+	return record.Mountpoint, nil
+}
+
+func (d *LocalDriver) Unmount(env dockerdriver.Env, unmountRequest dockerdriver.UnmountRequest) dockerdriver.ErrorResponse {
+	logger := env.Logger().Session("unmount", lager.Data{"volume": unmountRequest.Name, "id": unmountRequest.ID})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	if err := d.unmount(unmountRequest.Name); err != nil {
+		logger.Error("failed-unmounting-volume", err)
+		return dockerdriver.ErrorResponse{Err: err.Error(), ErrCode: dockerdriver.ErrorCodeOf(err)}
+	}
+	return dockerdriver.ErrorResponse{}
+}
+
+// unmount only takes the mountpoint away for the last mount.
+func (d *LocalDriver) unmount(name string) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	record, err := d.get(name)
+	if err != nil {
+		return err
+	}
+	if record.MountCount == 0 {
+		return dockerdriver.NewDriverError(dockerdriver.ErrCodeNotFound, fmt.Sprintf("Volume %s is not mounted", name))
+	}
+
+	if record.MountCount == 1 {
+		if err := d.detach(record.Mountpoint); err != nil {
+			return err
+		}
+	}
+
+	return d.store.Update(name, func(record *driverstate.VolumeRecord) error {
+		record.MountCount--
+		return nil
+	})
+}
+
+func (d *LocalDriver) Path(env dockerdriver.Env, pathRequest dockerdriver.PathRequest) dockerdriver.PathResponse {
+	volume, err := d.volumeInfo(pathRequest.Name)
+	if err != nil {
+		env.Logger().Session("path").Error("failed-getting-volume", err, lager.Data{"volume": pathRequest.Name})
+		return dockerdriver.PathResponse{Err: err.Error(), ErrCode: dockerdriver.ErrorCodeOf(err)}
+	}
+	return dockerdriver.PathResponse{Mountpoint: volume.Mountpoint}
+}
+
+func (d *LocalDriver) Get(env dockerdriver.Env, getRequest dockerdriver.GetRequest) dockerdriver.GetResponse {
+	volume, err := d.volumeInfo(getRequest.Name)
+	if err != nil {
+		env.Logger().Session("get").Error("failed-getting-volume", err, lager.Data{"volume": getRequest.Name})
+		return dockerdriver.GetResponse{Err: err.Error(), ErrCode: dockerdriver.ErrorCodeOf(err)}
+	}
+	return dockerdriver.GetResponse{Volume: volume}
+}
+
+func (d *LocalDriver) List(env dockerdriver.Env) dockerdriver.ListResponse {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	records, err := d.store.List()
+	if err != nil {
+		env.Logger().Session("list").Error("failed-listing-volumes", err)
+		return dockerdriver.ListResponse{Err: err.Error()}
+	}
+
+	volumes := make([]dockerdriver.VolumeInfo, 0, len(records))
+	for _, record := range records {
+		volumes = append(volumes, volumeInfo(record))
+	}
+	return dockerdriver.ListResponse{Volumes: volumes}
+}
+
+func (d *LocalDriver) volumeInfo(name string) (dockerdriver.VolumeInfo, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	record, err := d.get(name)
+	if err != nil {
+		return dockerdriver.VolumeInfo{}, err
+	}
+	return volumeInfo(record), nil
+}
+
+// volumeInfo only gives the mountpoint of a mounted volume, since it doesn't
+// exist otherwise.
+func volumeInfo(record driverstate.VolumeRecord) dockerdriver.VolumeInfo {
+	volume := record.VolumeInfo()
+	if volume.MountCount == 0 {
+		volume.Mountpoint = ""
+	}
+	return volume
+}
+
+func (d *LocalDriver) get(name string) (driverstate.VolumeRecord, error) {
+	if err := validateName(name); err != nil {
+		return driverstate.VolumeRecord{}, err
+	}
+
+	record, err := d.store.Get(name)
+	if errors.Is(err, dockerdriver.ErrNotFound) {
+		return driverstate.VolumeRecord{}, dockerdriver.NewDriverError(dockerdriver.ErrCodeNotFound, fmt.Sprintf("Volume %s does not exist", name))
+	}
+	return record, err
+}
+
+// attach makes the volume's directory appear at its mountpoint.
+func (d *LocalDriver) attach(record driverstate.VolumeRecord) error {
+	dataDir := d.dataDir(record.Name)
+
+	if !d.config.BindMounts {
+		// a link left by a crash may point anywhere; replace it
+		if err := os.Remove(record.Mountpoint); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return os.Symlink(dataDir, record.Mountpoint)
+	}
+
+	options, err := parseOptions(record.Opts)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(record.Mountpoint, 0755); err != nil {
+		return err
+	}
+	if err := bindMount(dataDir, record.Mountpoint, options.readonly); err != nil {
+		os.Remove(record.Mountpoint)
+		return err
+	}
+	return nil
+}
+
+// detach takes away a mountpoint made by attach.
+func (d *LocalDriver) detach(mountpoint string) error {
+	if d.config.BindMounts {
+		if err := unmount(mountpoint); err != nil && !errors.Is(err, os.ErrNotExist) && !isNotMounted(err) {
+			return err
+		}
+	}
+	if err := os.Remove(mountpoint); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Reconciler repairs the driver's bind mounts against the mount table. Run
+// it before serving, since it updates the state directly.
+func (d *LocalDriver) Reconciler(logger lager.Logger, clock clock.Clock, policy driverreconcile.Policy) *driverreconcile.Reconciler {
+	return driverreconcile.NewReconciler(logger, d.store, mounter{d}, clock, driverreconcile.Config{
+		MountRoot: d.config.MountDir,
+		Policy:    policy,
+	})
+}
+
+type mounter struct {
+	driver *LocalDriver
+}
+
+func (m mounter) Remount(env dockerdriver.Env, record driverstate.VolumeRecord) error {
+	m.driver.lock.Lock()
+	defer m.driver.lock.Unlock()
+
+	m.driver.detach(record.Mountpoint)
+	return m.driver.attach(record)
+}
+
+func (m mounter) Unmount(env dockerdriver.Env, mountpoint string) error {
+	m.driver.lock.Lock()
+	defer m.driver.lock.Unlock()
+
+	return m.driver.detach(mountpoint)
+}
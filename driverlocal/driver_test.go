@@ -0,0 +1,200 @@
+package driverlocal_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/dockerdriver/driverlocal"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LocalDriver", func() {
+	var (
+		testLogger *lagertest.TestLogger
+		env        dockerdriver.Env
+		config     driverlocal.Config
+		subject    *driverlocal.LocalDriver
+	)
+
+	BeforeEach(func() {
+		testLogger = lagertest.NewTestLogger("driverlocal-test")
+		env = driverhttp.NewHttpDriverEnv(testLogger, context.Background())
+		config = driverlocal.Config{RootDir: GinkgoT().TempDir()}
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		subject, err = driverlocal.NewLocalDriver(testLogger, config)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	create := func(volume string, opts map[string]interface{}) dockerdriver.ErrorResponse {
+		return subject.Create(env, dockerdriver.CreateRequest{Name: volume, Opts: opts})
+	}
+
+	mount := func(volume string) dockerdriver.MountResponse {
+		return subject.Mount(env, dockerdriver.MountRequest{Name: volume})
+	}
+
+	unmount := func(volume string) dockerdriver.ErrorResponse {
+		return subject.Unmount(env, dockerdriver.UnmountRequest{Name: volume})
+	}
+
+	remove := func(volume string) dockerdriver.ErrorResponse {
+		return subject.Remove(env, dockerdriver.RemoveRequest{Name: volume})
+	}
+
+	It("should report local scope", func() {
+		Expect(subject.Capabilities(env).Capabilities.Scope).To(Equal("local"))
+	})
+
+	It("should expose a mounted volume's directory at its mountpoint until it is unmounted", func() {
+		Expect(create("some-volume", nil).Err).To(BeEmpty())
+
+		mountResponse := mount("some-volume")
+		Expect(mountResponse.Err).To(BeEmpty())
+		Expect(mountResponse.Mountpoint).To(Equal(filepath.Join(config.RootDir, "mounts", "some-volume")))
+		Expect(os.WriteFile(filepath.Join(mountResponse.Mountpoint, "some-file"), []byte("data"), 0644)).To(Succeed())
+
+		Expect(unmount("some-volume").Err).To(BeEmpty())
+		Expect(mountResponse.Mountpoint).NotTo(BeAnExistingFile())
+
+		mountResponse = mount("some-volume")
+		Expect(os.ReadFile(filepath.Join(mountResponse.Mountpoint, "some-file"))).To(Equal([]byte("data")))
+	})
+
+	It("should keep the mountpoint until the last unmount", func() {
+		create("some-volume", nil)
+		mountpoint := mount("some-volume").Mountpoint
+		mount("some-volume")
+
+		getResponse := subject.Get(env, dockerdriver.GetRequest{Name: "some-volume"})
+		Expect(getResponse.Volume.MountCount).To(Equal(2))
+		Expect(getResponse.Volume.Mountpoint).To(Equal(mountpoint))
+
+		Expect(unmount("some-volume").Err).To(BeEmpty())
+		Expect(mountpoint).To(BeADirectory())
+
+		Expect(unmount("some-volume").Err).To(BeEmpty())
+		Expect(mountpoint).NotTo(BeAnExistingFile())
+
+		Expect(subject.Path(env, dockerdriver.PathRequest{Name: "some-volume"}).Mountpoint).To(BeEmpty())
+		errResponse := unmount("some-volume")
+		Expect(errResponse.Err).To(ContainSubstring("is not mounted"))
+		Expect(errResponse.ErrCode).To(Equal(dockerdriver.ErrCodeNotFound))
+	})
+
+	It("should list its volumes", func() {
+		create("volume-b", nil)
+		create("volume-a", nil)
+		mount("volume-a")
+
+		listResponse := subject.List(env)
+		Expect(listResponse.Err).To(BeEmpty())
+		Expect(listResponse.Volumes).To(HaveExactElements(
+			SatisfyAll(HaveField("Name", "volume-a"), HaveField("MountCount", 1)),
+			SatisfyAll(HaveField("Name", "volume-b"), HaveField("Mountpoint", "")),
+		))
+	})
+
+	It("should succeed in creating a volume that exists", func() {
+		Expect(create("some-volume", nil).Err).To(BeEmpty())
+		Expect(create("some-volume", nil).Err).To(BeEmpty())
+	})
+
+	It("should apply the mode option", func() {
+		Expect(create("some-volume", map[string]interface{}{"mode": "0700"}).Err).To(BeEmpty())
+
+		info, err := os.Stat(mount("some-volume").Mountpoint)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0700)))
+	})
+
+	DescribeTable("should reject bad create requests",
+		func(volume string, opts map[string]interface{}, message string) {
+			errResponse := create(volume, opts)
+			Expect(errResponse.Err).To(ContainSubstring(message))
+			Expect(errResponse.ErrCode).To(Equal(dockerdriver.ErrCodeInvalidOptions))
+		},
+		Entry("a name that leaves the root", "../escape", nil, "invalid volume name"),
+		Entry("unsupported options", "some-volume", map[string]interface{}{"username": "x", "password": "y"}, "unsupported options: password, username"),
+		Entry("a bad mode", "some-volume", map[string]interface{}{"mode": "rwx"}, "invalid value for option mode"),
+		Entry("readonly without bind mounts", "some-volume", map[string]interface{}{"readonly": true}, "readonly needs the driver to bind mount"),
+	)
+
+	It("should report a volume that doesn't exist", func() {
+		mountResponse := mount("missing-volume")
+		Expect(mountResponse.Err).To(Equal("Volume missing-volume does not exist"))
+		Expect(mountResponse.ErrCode).To(Equal(dockerdriver.ErrCodeNotFound))
+
+		Expect(unmount("missing-volume").Err).To(Equal("Volume missing-volume does not exist"))
+		Expect(subject.Get(env, dockerdriver.GetRequest{Name: "missing-volume"}).ErrCode).To(Equal(dockerdriver.ErrCodeNotFound))
+	})
+
+	Describe("Remove", func() {
+		It("should delete the volume's data", func() {
+			create("some-volume", nil)
+			mountpoint := mount("some-volume").Mountpoint
+			Expect(os.WriteFile(filepath.Join(mountpoint, "some-file"), []byte("data"), 0644)).To(Succeed())
+			unmount("some-volume")
+
+			Expect(remove("some-volume").Err).To(BeEmpty())
+			Expect(filepath.Join(config.RootDir, "volumes", "some-volume")).NotTo(BeAnExistingFile())
+			Expect(subject.List(env).Volumes).To(BeEmpty())
+		})
+
+		It("should refuse a volume that is mounted", func() {
+			create("some-volume", nil)
+			mount("some-volume")
+
+			errResponse := remove("some-volume")
+			Expect(errResponse.ErrCode).To(Equal(dockerdriver.ErrCodeInUse))
+		})
+
+		It("should keep a volume it fails to remove whole", func() {
+			create("some-volume", nil)
+			mountpoint := mount("some-volume").Mountpoint
+			Expect(os.WriteFile(filepath.Join(mountpoint, "some-file"), []byte("data"), 0644)).To(Succeed())
+			unmount("some-volume")
+
+			By("failing to save the state")
+			stateTemp := filepath.Join(config.RootDir, "volumes.json.tmp")
+			Expect(os.Mkdir(stateTemp, 0755)).To(Succeed())
+			Expect(remove("some-volume").Err).NotTo(BeEmpty())
+			Expect(subject.Get(env, dockerdriver.GetRequest{Name: "some-volume"}).Err).To(BeEmpty())
+			Expect(os.ReadFile(filepath.Join(config.RootDir, "volumes", "some-volume", "some-file"))).To(Equal([]byte("data")))
+
+			By("retrying once the state can be saved")
+			Expect(os.Remove(stateTemp)).To(Succeed())
+			Expect(remove("some-volume").Err).To(BeEmpty())
+			Expect(os.ReadDir(filepath.Join(config.RootDir, "volumes"))).To(BeEmpty())
+		})
+
+		It("should succeed for a volume that doesn't exist", func() {
+			Expect(remove("missing-volume").Err).To(BeEmpty())
+		})
+	})
+
+	It("should keep its volumes when it is restarted", func() {
+		create("some-volume", nil)
+		mountpoint := mount("some-volume").Mountpoint
+
+		restarted, err := driverlocal.NewLocalDriver(testLogger, config)
+		Expect(err).NotTo(HaveOccurred())
+
+		getResponse := restarted.Get(env, dockerdriver.GetRequest{Name: "some-volume"})
+		Expect(getResponse.Volume.MountCount).To(Equal(1))
+		Expect(restarted.Unmount(env, dockerdriver.UnmountRequest{Name: "some-volume"}).Err).To(BeEmpty())
+		Expect(mountpoint).NotTo(BeAnExistingFile())
+	})
+
+	It("should not log option values", func() {
+		create("some-volume", map[string]interface{}{"password": "secret"})
+		Expect(testLogger.Buffer().Contents()).NotTo(ContainSubstring("secret"))
+	})
+})
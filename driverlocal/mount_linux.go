@@ -0,0 +1,34 @@
+package driverlocal
+
+import (
+	"errors"
+	"syscall"
+)
+
+func bindMount(source, target string, readonly bool) error {
+	if err := syscall.Mount(source, target, "", syscall.MS_BIND, ""); err != nil {
+		return err
+	}
+	if !readonly {
+		return nil
+	}
+
+	// a bind mount only becomes read only when it is remounted
+	if err := syscall.Mount("", target, "", syscall.MS_BIND|syscall.MS_REMOUNT|syscall.MS_RDONLY, ""); err != nil {
+		syscall.Unmount(target, syscall.MNT_DETACH)
+		return err
+	}
+	return nil
+}
+
+// unmount detaches the mount straight away, leaving the kernel to finish
+// once files still open on it are closed.
+func unmount(target string) error {
+	return syscall.Unmount(target, syscall.MNT_DETACH)
+}
+
+// isNotMounted is true for the error from unmounting a path nothing is
+// mounted on.
+func isNotMounted(err error) bool {
+	return errors.Is(err, syscall.EINVAL)
+}
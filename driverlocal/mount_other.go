@@ -0,0 +1,19 @@
+//go:build !linux
+
+package driverlocal
+
+import "errors"
+
+var errBindMountsUnsupported = errors.New("bind mounts are only supported on linux")
+
+func bindMount(source, target string, readonly bool) error {
+	return errBindMountsUnsupported
+}
+
+func unmount(target string) error {
+	return errBindMountsUnsupported
+}
+
+func isNotMounted(err error) bool {
+	return false
+}
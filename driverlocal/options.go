@@ -0,0 +1,78 @@
+package driverlocal
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"code.cloudfoundry.org/dockerdriver"
+)
+
+// validVolumeName is docker's own rule for volume names; it also keeps names
+// from reaching outside the root directory.
+var validVolumeName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// volumeOptions are the Create opts the driver understands:
+//
+//	uid, gid  owner of the volume's directory
+//	mode      permissions of the volume's directory, in octal, e.g. "0750"
+//	readonly  mount the volume read only; needs bind mounts
+//
+// Values may be strings or JSON numbers and booleans.
+type volumeOptions struct {
+	uid      int
+	gid      int
+	mode     os.FileMode
+	readonly bool
+}
+
+func parseOptions(opts map[string]interface{}) (volumeOptions, error) {
+	options := volumeOptions{uid: -1, gid: -1, mode: 0755}
+
+	var unsupported []string
+	for key, value := range opts {
+		var err error
+		switch key {
+		case "uid":
+			options.uid, err = strconv.Atoi(optionString(value))
+		case "gid":
+			options.gid, err = strconv.Atoi(optionString(value))
+		case "mode":
+			var mode uint64
+			mode, err = strconv.ParseUint(optionString(value), 8, 32)
+			options.mode = os.FileMode(mode).Perm()
+		case "readonly":
+			options.readonly, err = strconv.ParseBool(optionString(value))
+		default:
+			unsupported = append(unsupported, key)
+			continue
+		}
+		if err != nil {
+			return volumeOptions{}, invalidOptions(fmt.Sprintf("invalid value for option %s: %v", key, value))
+		}
+	}
+
+	if len(unsupported) > 0 {
+		slices.Sort(unsupported)
+		return volumeOptions{}, invalidOptions("unsupported options: " + strings.Join(unsupported, ", "))
+	}
+	return options, nil
+}
+
+func optionString(value interface{}) string {
+	return fmt.Sprint(value)
+}
+
+func validateName(name string) error {
+	if !validVolumeName.MatchString(name) {
+		return invalidOptions(fmt.Sprintf("invalid volume name %q", name))
+	}
+	return nil
+}
+
+func invalidOptions(message string) error {
+	return dockerdriver.NewDriverError(dockerdriver.ErrCodeInvalidOptions, message)
+}
@@ -0,0 +1,142 @@
+// Package driverlock serializes a Driver's operations on each volume, so that
+// drivers don't each have to guard against concurrent requests for the same
+// volume.
+package driverlock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/lager/v3"
+)
+
+// QueueFullError is returned, as an in-use error, when a volume already has
+// as many operations waiting as allowed.
+type QueueFullError struct {
+	Volume   string
+	MaxQueue int
+}
+
+func (e *QueueFullError) Error() string {
+	return fmt.Sprintf("volume %s is busy: %d operations are already waiting for it", e.Volume, e.MaxQueue)
+}
+
+func (e *QueueFullError) Is(target error) bool {
+	return target == dockerdriver.ErrInUse
+}
+
+type Option func(*serializedDriver)
+
+// WithMaxQueue limits the operations that may wait for a volume at once;
+// zero, the default, means no limit.
+func WithMaxQueue(maxQueue int) Option {
+	return func(d *serializedDriver) {
+		d.locks.maxQueue = maxQueue
+	}
+}
+
+type serializedDriver struct {
+	driver dockerdriver.Driver
+	locks  *volumeLocks
+}
+
+// NewSerializedDriver wraps driver so that Create, Remove, Mount, Unmount,
+// Get and Path run one at a time for each volume name, while different
+// volumes still run in parallel. Callers give up waiting when their Env's
+// context is done. Activate, List and Capabilities are passed straight
+// through.
+func NewSerializedDriver(driver dockerdriver.Driver, opts ...Option) dockerdriver.Driver {
+	d := &serializedDriver{driver: driver, locks: newVolumeLocks(0)}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+func (d *serializedDriver) lock(env dockerdriver.Env, volume string) (func(), string, dockerdriver.ErrorCode) {
+	release, err := d.locks.acquire(env.Context(), volume)
+	if err != nil {
+		env.Logger().Error("failed-waiting-for-volume", err, lager.Data{"volume": volume})
+		return nil, err.Error(), errorCode(err)
+	}
+	return release, "", ""
+}
+
+func errorCode(err error) dockerdriver.ErrorCode {
+	switch {
+	case errors.Is(err, dockerdriver.ErrInUse):
+		return dockerdriver.ErrCodeInUse
+	case errors.Is(err, context.DeadlineExceeded):
+		return dockerdriver.ErrCodeTimeout
+	default:
+		return ""
+	}
+}
+
+func (d *serializedDriver) Activate(env dockerdriver.Env) dockerdriver.ActivateResponse {
+	return d.driver.Activate(env)
+}
+
+func (d *serializedDriver) List(env dockerdriver.Env) dockerdriver.ListResponse {
+	return d.driver.List(env)
+}
+
+func (d *serializedDriver) Capabilities(env dockerdriver.Env) dockerdriver.CapabilitiesResponse {
+	return d.driver.Capabilities(env)
+}
+
+func (d *serializedDriver) Create(env dockerdriver.Env, createRequest dockerdriver.CreateRequest) dockerdriver.ErrorResponse {
+	release, message, code := d.lock(env, createRequest.Name)
+	if release == nil {
+		return dockerdriver.ErrorResponse{Err: message, ErrCode: code}
+	}
+	defer release()
+	return d.driver.Create(env, createRequest)
+}
+
+func (d *serializedDriver) Remove(env dockerdriver.Env, removeRequest dockerdriver.RemoveRequest) dockerdriver.ErrorResponse {
+	release, message, code := d.lock(env, removeRequest.Name)
+	if release == nil {
+		return dockerdriver.ErrorResponse{Err: message, ErrCode: code}
+	}
+	defer release()
+	return d.driver.Remove(env, removeRequest)
+}
+
+func (d *serializedDriver) Mount(env dockerdriver.Env, mountRequest dockerdriver.MountRequest) dockerdriver.MountResponse {
+	release, message, code := d.lock(env, mountRequest.Name)
+	if release == nil {
+		return dockerdriver.MountResponse{Err: message, ErrCode: code}
+	}
+	defer release()
+	return d.driver.Mount(env, mountRequest)
+}
+
+func (d *serializedDriver) Unmount(env dockerdriver.Env, unmountRequest dockerdriver.UnmountRequest) dockerdriver.ErrorResponse {
+	release, message, code := d.lock(env, unmountRequest.Name)
+	if release == nil {
+		return dockerdriver.ErrorResponse{Err: message, ErrCode: code}
+	}
+	defer release()
+	return d.driver.Unmount(env, unmountRequest)
+}
+
+func (d *serializedDriver) Get(env dockerdriver.Env, getRequest dockerdriver.GetRequest) dockerdriver.GetResponse {
+	release, message, code := d.lock(env, getRequest.Name)
+	if release == nil {
+		return dockerdriver.GetResponse{Err: message, ErrCode: code}
+	}
+	defer release()
+	return d.driver.Get(env, getRequest)
+}
+
+func (d *serializedDriver) Path(env dockerdriver.Env, pathRequest dockerdriver.PathRequest) dockerdriver.PathResponse {
+	release, message, code := d.lock(env, pathRequest.Name)
+	if release == nil {
+		return dockerdriver.PathResponse{Err: message, ErrCode: code}
+	}
+	defer release()
+	return d.driver.Path(env, pathRequest)
+}
@@ -0,0 +1,228 @@
+package driverlock_test
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/dockerdriverfakes"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/dockerdriver/driverlock"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SerializedDriver", func() {
+	var (
+		testLogger *lagertest.TestLogger
+		env        dockerdriver.Env
+		fakeDriver *dockerdriverfakes.FakeDriver
+		opts       []driverlock.Option
+		subject    dockerdriver.Driver
+
+		mu         sync.Mutex
+		entered    []string
+		unblock    chan struct{}
+		unblockAll func()
+		inFlight   int
+		maxSeen    int
+		background sync.WaitGroup
+	)
+
+	BeforeEach(func() {
+		testLogger = lagertest.NewTestLogger("driverlock-test")
+		env = driverhttp.NewHttpDriverEnv(testLogger, context.Background())
+		opts = nil
+
+		entered = nil
+		inFlight = 0
+		maxSeen = 0
+		unblock = make(chan struct{})
+		unblockAll = sync.OnceFunc(func() { close(unblock) })
+
+		fakeDriver = &dockerdriverfakes.FakeDriver{}
+		fakeDriver.MountStub = func(_ dockerdriver.Env, mountRequest dockerdriver.MountRequest) dockerdriver.MountResponse {
+			mu.Lock()
+			entered = append(entered, mountRequest.Name)
+			inFlight++
+			maxSeen = max(maxSeen, inFlight)
+			mu.Unlock()
+
+			<-unblock
+
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+			return dockerdriver.MountResponse{Mountpoint: "/mnt/" + mountRequest.Name}
+		}
+	})
+
+	JustBeforeEach(func() {
+		subject = driverlock.NewSerializedDriver(fakeDriver, opts...)
+	})
+
+	AfterEach(func() {
+		unblockAll()
+		background.Wait()
+	})
+
+	enteredVolumes := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string{}, entered...)
+	}
+
+	mountInBackground := func(env dockerdriver.Env, volume string) <-chan dockerdriver.MountResponse {
+		responses := make(chan dockerdriver.MountResponse, 1)
+		background.Add(1)
+		go func() {
+			defer GinkgoRecover()
+			defer background.Done()
+			responses <- subject.Mount(env, dockerdriver.MountRequest{Name: volume})
+		}()
+		return responses
+	}
+
+	It("should run one operation at a time on the same volume", func() {
+		first := mountInBackground(env, "some-volume")
+		Eventually(enteredVolumes).Should(HaveLen(1))
+
+		second := mountInBackground(env, "some-volume")
+		Consistently(enteredVolumes, 100*time.Millisecond).Should(HaveLen(1))
+
+		unblock <- struct{}{}
+		Eventually(first).Should(Receive(HaveField("Mountpoint", "/mnt/some-volume")))
+		Eventually(enteredVolumes).Should(HaveLen(2))
+
+		unblock <- struct{}{}
+		Eventually(second).Should(Receive(HaveField("Mountpoint", "/mnt/some-volume")))
+
+		mu.Lock()
+		defer mu.Unlock()
+		Expect(maxSeen).To(Equal(1))
+	})
+
+	It("should run operations on different volumes in parallel", func() {
+		first := mountInBackground(env, "some-volume")
+		second := mountInBackground(env, "other-volume")
+		Eventually(enteredVolumes).Should(ConsistOf("some-volume", "other-volume"))
+
+		unblockAll()
+		Eventually(first).Should(Receive())
+		Eventually(second).Should(Receive())
+	})
+
+	It("should serialize different operations on the same volume", func() {
+		fakeDriver.UnmountStub = func(dockerdriver.Env, dockerdriver.UnmountRequest) dockerdriver.ErrorResponse {
+			mu.Lock()
+			entered = append(entered, "unmount")
+			mu.Unlock()
+			return dockerdriver.ErrorResponse{}
+		}
+
+		mounted := mountInBackground(env, "some-volume")
+		Eventually(enteredVolumes).Should(HaveLen(1))
+
+		unmounted := make(chan dockerdriver.ErrorResponse, 1)
+		go func() {
+			unmounted <- subject.Unmount(env, dockerdriver.UnmountRequest{Name: "some-volume"})
+		}()
+		Consistently(unmounted, 100*time.Millisecond).ShouldNot(Receive())
+
+		unblockAll()
+		Eventually(mounted).Should(Receive())
+		Eventually(unmounted).Should(Receive(Equal(dockerdriver.ErrorResponse{})))
+		Expect(enteredVolumes()).To(Equal([]string{"some-volume", "unmount"}))
+	})
+
+	Context("when the caller gives up waiting", func() {
+		It("should return the context's error without calling the driver", func() {
+			mountInBackground(env, "some-volume")
+			Eventually(enteredVolumes).Should(HaveLen(1))
+
+			ctx, cancel := context.WithCancel(context.Background())
+			waiting := mountInBackground(driverhttp.NewHttpDriverEnv(testLogger, ctx), "some-volume")
+			cancel()
+
+			var response dockerdriver.MountResponse
+			Eventually(waiting).Should(Receive(&response))
+			Expect(response.Err).To(ContainSubstring("context canceled"))
+			Expect(fakeDriver.MountCallCount()).To(Equal(1))
+
+			unblockAll()
+		})
+
+		It("should report a timeout when its deadline passes", func() {
+			mountInBackground(env, "some-volume")
+			Eventually(enteredVolumes).Should(HaveLen(1))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			response := subject.Mount(driverhttp.NewHttpDriverEnv(testLogger, ctx), dockerdriver.MountRequest{Name: "some-volume"})
+			Expect(response.ErrCode).To(Equal(dockerdriver.ErrCodeTimeout))
+
+			unblockAll()
+		})
+	})
+
+	Context("when the queue for a volume is limited", func() {
+		BeforeEach(func() {
+			opts = []driverlock.Option{driverlock.WithMaxQueue(1)}
+		})
+
+		It("should turn away operations beyond the limit as in use", func() {
+			mountInBackground(env, "some-volume")
+			Eventually(enteredVolumes).Should(HaveLen(1))
+			queued := mountInBackground(env, "some-volume")
+			Consistently(queued, 50*time.Millisecond).ShouldNot(Receive())
+
+			response := subject.Mount(env, dockerdriver.MountRequest{Name: "some-volume"})
+			Expect(response.ErrCode).To(Equal(dockerdriver.ErrCodeInUse))
+			Expect(response.DriverError()).To(MatchError(dockerdriver.ErrInUse))
+
+			By("still accepting operations on other volumes")
+			other := mountInBackground(env, "other-volume")
+			Eventually(enteredVolumes).Should(ContainElement("other-volume"))
+
+			unblockAll()
+			Eventually(queued).Should(Receive(HaveField("Err", "")))
+			Eventually(other).Should(Receive(HaveField("Err", "")))
+		})
+
+		It("should not count a caller that takes a free lock as queued", func() {
+			// the callers need to overlap, even on a single cpu
+			DeferCleanup(runtime.GOMAXPROCS, runtime.GOMAXPROCS(4))
+
+			var wg sync.WaitGroup
+			for i := 0; i < 2; i++ {
+				wg.Add(1)
+				go func() {
+					defer GinkgoRecover()
+					defer wg.Done()
+					for j := 0; j < 20000; j++ {
+						Expect(subject.Remove(env, dockerdriver.RemoveRequest{Name: "some-volume"}).Err).To(BeEmpty())
+					}
+				}()
+			}
+			wg.Wait()
+		})
+	})
+
+	It("should pass calls that name no volume straight through", func() {
+		fakeDriver.ListReturns(dockerdriver.ListResponse{Volumes: []dockerdriver.VolumeInfo{{Name: "some-volume"}}})
+		fakeDriver.CapabilitiesReturns(dockerdriver.CapabilitiesResponse{Capabilities: dockerdriver.CapabilityInfo{Scope: "local"}})
+		fakeDriver.ActivateReturns(dockerdriver.ActivateResponse{Implements: []string{"VolumeDriver"}})
+
+		mountInBackground(env, "some-volume")
+		Eventually(enteredVolumes).Should(HaveLen(1))
+
+		Expect(subject.List(env).Volumes).To(HaveLen(1))
+		Expect(subject.Capabilities(env).Capabilities.Scope).To(Equal("local"))
+		Expect(subject.Activate(env).Implements).To(ConsistOf("VolumeDriver"))
+
+		unblockAll()
+	})
+})
@@ -0,0 +1,13 @@
+package driverlock_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestDriverLock(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Driver Lock Suite")
+}
@@ -0,0 +1,89 @@
+package driverlock
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// volumeLocks hands out one lock per volume name, and forgets a volume once
+// nobody holds or waits for its lock.
+type volumeLocks struct {
+	mu       sync.Mutex
+	volumes  map[string]*volumeLock
+	maxQueue int
+}
+
+// volumeLock is handed straight from its holder to the first waiter, so
+// waiters only ever counts callers that are still blocked.
+type volumeLock struct {
+	held    bool
+	waiters []chan struct{}
+}
+
+func newVolumeLocks(maxQueue int) *volumeLocks {
+	return &volumeLocks{volumes: map[string]*volumeLock{}, maxQueue: maxQueue}
+}
+
+// acquire waits for the volume's lock until ctx is done, and returns the
+// func that releases it. Only callers that have to wait count towards
+// maxQueue.
+func (l *volumeLocks) acquire(ctx context.Context, volume string) (func(), error) {
+	l.mu.Lock()
+	lock, ok := l.volumes[volume]
+	if !ok {
+		lock = &volumeLock{}
+		l.volumes[volume] = lock
+	}
+
+	if !lock.held {
+		lock.held = true
+		l.mu.Unlock()
+		return l.releaser(volume, lock), nil
+	}
+
+	if l.maxQueue > 0 && len(lock.waiters) >= l.maxQueue {
+		l.mu.Unlock()
+		return nil, &QueueFullError{Volume: volume, MaxQueue: l.maxQueue}
+	}
+	handedOff := make(chan struct{})
+	lock.waiters = append(lock.waiters, handedOff)
+	l.mu.Unlock()
+
+	select {
+	case <-handedOff:
+		return l.releaser(volume, lock), nil
+	case <-ctx.Done():
+	}
+
+	l.mu.Lock()
+	if i := slices.Index(lock.waiters, handedOff); i >= 0 {
+		lock.waiters = slices.Delete(lock.waiters, i, i+1)
+		l.mu.Unlock()
+		return nil, ctx.Err()
+	}
+	l.mu.Unlock()
+
+	// the lock was handed over as ctx finished; pass it on
+	l.release(volume, lock)
+	return nil, ctx.Err()
+}
+
+func (l *volumeLocks) releaser(volume string, lock *volumeLock) func() {
+	return func() {
+		l.release(volume, lock)
+	}
+}
+
+func (l *volumeLocks) release(volume string, lock *volumeLock) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(lock.waiters) > 0 {
+		close(lock.waiters[0])
+		lock.waiters = lock.waiters[1:]
+		return
+	}
+	lock.held = false
+	delete(l.volumes, volume)
+}
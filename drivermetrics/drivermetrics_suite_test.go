@@ -0,0 +1,13 @@
+package drivermetrics_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestDriverMetrics(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Driver Metrics Suite")
+}
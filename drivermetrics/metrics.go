@@ -0,0 +1,106 @@
+package drivermetrics
+
+type CounterVec struct {
+	family *family
+}
+
+// WithLabelValues takes values in the order the label names were registered.
+func (v *CounterVec) WithLabelValues(labelValues ...string) *Counter {
+	return &Counter{family: v.family, series: v.family.with(labelValues)}
+}
+
+type Counter struct {
+	family *family
+	series *series
+}
+
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add ignores negative deltas; counters only go up.
+func (c *Counter) Add(delta float64) {
+	if delta < 0 {
+		return
+	}
+	c.family.update(c.series, func(s *series) { s.value += delta })
+}
+
+func (c *Counter) Value() float64 {
+	var value float64
+	c.family.read(c.series, func(s *series) { value = s.value })
+	return value
+}
+
+type GaugeVec struct {
+	family *family
+}
+
+func (v *GaugeVec) WithLabelValues(labelValues ...string) *Gauge {
+	return &Gauge{family: v.family, series: v.family.with(labelValues)}
+}
+
+type Gauge struct {
+	family *family
+	series *series
+}
+
+func (g *Gauge) Set(value float64) {
+	g.family.update(g.series, func(s *series) { s.value = value })
+}
+
+func (g *Gauge) Add(delta float64) {
+	g.family.update(g.series, func(s *series) { s.value += delta })
+}
+
+func (g *Gauge) Inc() {
+	g.Add(1)
+}
+
+func (g *Gauge) Dec() {
+	g.Add(-1)
+}
+
+func (g *Gauge) Value() float64 {
+	var value float64
+	g.family.read(g.series, func(s *series) { value = s.value })
+	return value
+}
+
+type HistogramVec struct {
+	family *family
+}
+
+func (v *HistogramVec) WithLabelValues(labelValues ...string) *Histogram {
+	return &Histogram{family: v.family, series: v.family.with(labelValues)}
+}
+
+type Histogram struct {
+	family *family
+	series *series
+}
+
+func (h *Histogram) Observe(value float64) {
+	h.family.update(h.series, func(s *series) {
+		s.value += value
+		s.count++
+		for i, upperBound := range h.family.buckets {
+			if value <= upperBound {
+				s.bucketCounts[i]++
+				return
+			}
+		}
+	})
+}
+
+func (h *Histogram) Count() uint64 {
+	var count uint64
+	h.family.read(h.series, func(s *series) { count = s.count })
+	return count
+}
+
+func (h *Histogram) Sum() float64 {
+	var sum float64
+	h.family.read(h.series, func(s *series) { sum = s.value })
+	return sum
+}
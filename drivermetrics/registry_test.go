@@ -0,0 +1,92 @@
+package drivermetrics_test
+
+import (
+	"bytes"
+	"net/http/httptest"
+
+	"code.cloudfoundry.org/dockerdriver/drivermetrics"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Registry", func() {
+	var registry *drivermetrics.Registry
+
+	BeforeEach(func() {
+		registry = drivermetrics.NewRegistry()
+	})
+
+	text := func() string {
+		var buffer bytes.Buffer
+		Expect(registry.WriteText(&buffer)).To(Succeed())
+		return buffer.String()
+	}
+
+	It("should write counters and gauges in the text format", func() {
+		requests := registry.Counter("requests_total", "Requests by route.", "route")
+		requests.WithLabelValues("mount").Add(2)
+		requests.WithLabelValues("get").Inc()
+
+		inFlight := registry.Gauge("in_flight", "Requests in progress.")
+		inFlight.WithLabelValues().Inc()
+		inFlight.WithLabelValues().Inc()
+		inFlight.WithLabelValues().Dec()
+
+		Expect(text()).To(Equal(`# HELP in_flight Requests in progress.
+# TYPE in_flight gauge
+in_flight 1
+# HELP requests_total Requests by route.
+# TYPE requests_total counter
+requests_total{route="get"} 1
+requests_total{route="mount"} 2
+`))
+	})
+
+	It("should write histograms with cumulative buckets", func() {
+		duration := registry.Histogram("duration_seconds", "Latency.", []float64{1, 0.1}, "route")
+		duration.WithLabelValues("mount").Observe(0.05)
+		duration.WithLabelValues("mount").Observe(0.5)
+		duration.WithLabelValues("mount").Observe(5)
+
+		Expect(text()).To(Equal(`# HELP duration_seconds Latency.
+# TYPE duration_seconds histogram
+duration_seconds_bucket{route="mount",le="0.1"} 1
+duration_seconds_bucket{route="mount",le="1"} 2
+duration_seconds_bucket{route="mount",le="+Inf"} 3
+duration_seconds_sum{route="mount"} 5.55
+duration_seconds_count{route="mount"} 3
+`))
+		Expect(duration.WithLabelValues("mount").Count()).To(Equal(uint64(3)))
+	})
+
+	It("should escape label values and help", func() {
+		registry.Counter("errors_total", "Errors\nby class.", "class").WithLabelValues(`a "quoted" \ value`).Inc()
+
+		Expect(text()).To(ContainSubstring(`# HELP errors_total Errors\nby class.`))
+		Expect(text()).To(ContainSubstring(`errors_total{class="a \"quoted\" \\ value"} 1`))
+	})
+
+	It("should return the existing metric when one is registered twice", func() {
+		registry.Counter("requests_total", "Requests.", "route").WithLabelValues("mount").Inc()
+		registry.Counter("requests_total", "Requests.", "route").WithLabelValues("mount").Inc()
+
+		Expect(registry.Counter("requests_total", "Requests.", "route").WithLabelValues("mount").Value()).To(Equal(2.0))
+	})
+
+	It("should panic when a metric is registered again with different labels", func() {
+		registry.Counter("requests_total", "Requests.", "route")
+
+		Expect(func() { registry.Counter("requests_total", "Requests.", "driver") }).To(Panic())
+		Expect(func() { registry.Gauge("requests_total", "Requests.", "route") }).To(Panic())
+	})
+
+	It("should serve the metrics over http", func() {
+		registry.Counter("requests_total", "Requests.").WithLabelValues().Inc()
+
+		res := httptest.NewRecorder()
+		registry.Handler().ServeHTTP(res, httptest.NewRequest("GET", "/metrics", nil))
+
+		Expect(res.Header().Get("Content-Type")).To(Equal(drivermetrics.TextContentType))
+		Expect(res.Body.String()).To(ContainSubstring("requests_total 1"))
+	})
+})
@@ -0,0 +1,13 @@
+package driverreconcile_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestDriverReconcile(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Driver Reconcile Suite")
+}
@@ -0,0 +1,49 @@
+package driverreconcile_test
+
+import (
+	"strings"
+
+	"code.cloudfoundry.org/dockerdriver/driverreconcile"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ParseMountInfo", func() {
+	It("should read each mount, whatever optional fields it has", func() {
+		mounts, err := driverreconcile.ParseMountInfo(strings.NewReader(
+			"22 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw\n" +
+				"36 22 0:45 /export /var/vcap/data/volumes/nfs/some-volume rw,nosuid master:1 shared:7 - nfs4 10.0.0.1:/export rw,vers=4.1\n" +
+				"37 22 0:46 / /mnt rw - tmpfs tmpfs rw\n",
+		))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(mounts).To(HaveLen(3))
+		Expect(mounts[1]).To(Equal(driverreconcile.MountInfo{
+			MountPoint: "/var/vcap/data/volumes/nfs/some-volume",
+			Root:       "/export",
+			FSType:     "nfs4",
+			Source:     "10.0.0.1:/export",
+			Options:    "rw,nosuid",
+		}))
+		Expect(mounts[2].FSType).To(Equal("tmpfs"))
+	})
+
+	It("should unescape the kernel's octal escapes", func() {
+		mounts, err := driverreconcile.ParseMountInfo(strings.NewReader(`36 22 0:45 / /mnt/some\040volume\134x rw - nfs4 server:/a\011b rw` + "\n"))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(mounts[0].MountPoint).To(Equal(`/mnt/some volume\x`))
+		Expect(mounts[0].Source).To(Equal("server:/a\tb"))
+	})
+
+	It("should skip blank lines", func() {
+		mounts, err := driverreconcile.ParseMountInfo(strings.NewReader("\n37 22 0:46 / /mnt rw - tmpfs tmpfs rw\n\n"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mounts).To(HaveLen(1))
+	})
+
+	It("should fail on a malformed line", func() {
+		_, err := driverreconcile.ParseMountInfo(strings.NewReader("37 22 0:46 / /mnt rw tmpfs tmpfs rw\n"))
+		Expect(err).To(MatchError(ContainSubstring("line 1 is malformed")))
+	})
+})
@@ -0,0 +1,262 @@
+// Package driverreconcile brings a driver's persisted volume state back in
+// line with the kernel's mount table, after a driver restart or a reboot.
+package driverreconcile
+
+import (
+	"context"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverstate"
+	"code.cloudfoundry.org/lager/v3"
+)
+
+// Problem is a way the state and the mount table can disagree.
+type Problem string
+
+const (
+	// ProblemMissingMount is a volume the state says is mounted, with no
+	// mount at its mountpoint.
+	ProblemMissingMount Problem = "missing-mount"
+	// ProblemUnknownMount is a mount under the mount root that the state
+	// doesn't show as mounted.
+	ProblemUnknownMount Problem = "unknown-mount"
+)
+
+// Action is what to do about a Problem.
+type Action string
+
+const (
+	// ActionFlag only logs the problem.
+	ActionFlag Action = "flag"
+	// ActionRemount mounts a missing mount again.
+	ActionRemount Action = "remount"
+	// ActionAdopt records an unknown mount as a mount of the volume whose
+	// mountpoint it is. Mounts no volume claims are only flagged.
+	ActionAdopt Action = "adopt"
+	// ActionCleanup marks a missing mount's volume as unmounted, or unmounts
+	// an unknown mount.
+	ActionCleanup Action = "cleanup"
+)
+
+// Policy says which Action to take for each Problem. An empty Action flags.
+type Policy struct {
+	MissingMount Action
+	UnknownMount Action
+}
+
+// DefaultPolicy suits a restart after a reboot: nothing still uses the
+// volumes that lost their mounts, and mounts that survived are kept.
+func DefaultPolicy() Policy {
+	return Policy{MissingMount: ActionCleanup, UnknownMount: ActionAdopt}
+}
+
+//go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -generate
+//counterfeiter:generate -o ../dockerdriverfakes/fake_mounter.go . Mounter
+
+// Mounter is the part of a driver that the reconciler needs to act on the
+// mount table.
+type Mounter interface {
+	// Remount mounts the volume at record.Mountpoint again.
+	Remount(env dockerdriver.Env, record driverstate.VolumeRecord) error
+	Unmount(env dockerdriver.Env, mountpoint string) error
+}
+
+type Config struct {
+	// MountRoot is the directory the driver mounts volumes under. Only
+	// mounts below it are treated as the driver's.
+	MountRoot string
+	Policy    Policy
+	// Interval, if set, reconciles again on a timer after the first run.
+	Interval time.Duration
+	// MountInfoPath defaults to DefaultMountInfoPath.
+	MountInfoPath string
+}
+
+// Result is one problem found and what was done about it.
+type Result struct {
+	Volume     string
+	Mountpoint string
+	Problem    Problem
+	Action     Action
+	Err        error
+}
+
+// reconcileEnv is the Env the Mounter is called with.
+type reconcileEnv struct {
+	logger lager.Logger
+	ctx    context.Context
+}
+
+func (e *reconcileEnv) Logger() lager.Logger {
+	return e.logger
+}
+
+func (e *reconcileEnv) Context() context.Context {
+	return e.ctx
+}
+
+type Reconciler struct {
+	logger  lager.Logger
+	store   driverstate.VolumeStore
+	mounter Mounter
+	clock   clock.Clock
+	config  Config
+}
+
+func NewReconciler(logger lager.Logger, store driverstate.VolumeStore, mounter Mounter, clock clock.Clock, config Config) *Reconciler {
+	if config.MountInfoPath == "" {
+		config.MountInfoPath = DefaultMountInfoPath
+	}
+	config.MountRoot = filepath.Clean(config.MountRoot)
+
+	return &Reconciler{
+		logger:  logger.Session("reconciler", lager.Data{"mount-root": config.MountRoot}),
+		store:   store,
+		mounter: mounter,
+		clock:   clock,
+		config:  config,
+	}
+}
+
+// Run reconciles before it is ready, so that a driver started after it
+// serves reconciled state, then again every Interval if one is set. It fails
+// if the first run can't read the state or the mount table.
+func (r *Reconciler) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if _, err := r.Reconcile(ctx); err != nil {
+		return err
+	}
+	close(ready)
+
+	if r.config.Interval <= 0 {
+		<-signals
+		return nil
+	}
+
+	ticker := r.clock.NewTicker(r.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-signals:
+			return nil
+		case <-ticker.C():
+			r.Reconcile(ctx)
+		}
+	}
+}
+
+// Reconcile compares the state with the mount table once, acts on each
+// problem it finds and reports what it did. Failing to act on one problem
+// doesn't stop the others; the failure is in its Result.
+func (r *Reconciler) Reconcile(ctx context.Context) ([]Result, error) {
+	logger := r.logger.Session("reconcile")
+	logger.Info("start")
+	defer logger.Info("end")
+
+	records, err := r.store.List()
+	if err != nil {
+		logger.Error("failed-listing-volumes", err)
+		return nil, err
+	}
+
+	mounts, err := ReadMountInfo(r.config.MountInfoPath)
+	if err != nil {
+		logger.Error("failed-reading-mount-table", err)
+		return nil, err
+	}
+
+	mounted := map[string]bool{}
+	for _, mount := range mounts {
+		mounted[filepath.Clean(mount.MountPoint)] = true
+	}
+
+	env := &reconcileEnv{logger: logger, ctx: ctx}
+	byMountpoint := map[string]driverstate.VolumeRecord{}
+	var results []Result
+
+	for _, record := range records {
+		if record.Mountpoint == "" {
+			continue
+		}
+		mountpoint := filepath.Clean(record.Mountpoint)
+		byMountpoint[mountpoint] = record
+
+		if record.MountCount > 0 && !mounted[mountpoint] {
+			results = append(results, r.missingMount(env, record))
+		}
+	}
+
+	for _, mountpoint := range slices.Sorted(maps.Keys(mounted)) {
+		if !r.underMountRoot(mountpoint) {
+			continue
+		}
+		if record, ok := byMountpoint[mountpoint]; ok && record.MountCount > 0 {
+			continue
+		}
+		results = append(results, r.unknownMount(env, mountpoint, byMountpoint))
+	}
+
+	for _, result := range results {
+		data := lager.Data{"volume": result.Volume, "mountpoint": result.Mountpoint, "problem": result.Problem, "action": result.Action}
+		if result.Err != nil {
+			logger.Error("failed-reconciling", result.Err, data)
+		} else {
+			logger.Info("reconciled", data)
+		}
+	}
+	logger.Info("summary", lager.Data{"volumes": len(records), "mounts": len(mounted), "problems": len(results)})
+
+	return results, nil
+}
+
+func (r *Reconciler) underMountRoot(mountpoint string) bool {
+	return strings.HasPrefix(mountpoint, r.config.MountRoot+string(filepath.Separator))
+}
+
+func (r *Reconciler) missingMount(env dockerdriver.Env, record driverstate.VolumeRecord) Result {
+	result := Result{Volume: record.Name, Mountpoint: record.Mountpoint, Problem: ProblemMissingMount, Action: r.config.Policy.MissingMount}
+
+	switch result.Action {
+	case ActionRemount:
+		result.Err = r.mounter.Remount(env, record)
+	case ActionCleanup:
+		result.Err = r.store.Update(record.Name, func(record *driverstate.VolumeRecord) error {
+			record.MountCount = 0
+			return nil
+		})
+	default:
+		result.Action = ActionFlag
+	}
+	return result
+}
+
+func (r *Reconciler) unknownMount(env dockerdriver.Env, mountpoint string, byMountpoint map[string]driverstate.VolumeRecord) Result {
+	result := Result{Mountpoint: mountpoint, Problem: ProblemUnknownMount, Action: r.config.Policy.UnknownMount}
+	record, known := byMountpoint[mountpoint]
+	if known {
+		result.Volume = record.Name
+	}
+
+	switch {
+	case result.Action == ActionAdopt && known:
+		result.Err = r.store.Update(record.Name, func(record *driverstate.VolumeRecord) error {
+			record.MountCount = 1
+			return nil
+		})
+	case result.Action == ActionCleanup:
+		result.Err = r.mounter.Unmount(env, mountpoint)
+	default:
+		result.Action = ActionFlag
+	}
+	return result
+}
@@ -0,0 +1,230 @@
+package driverreconcile_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/dockerdriver/dockerdriverfakes"
+	"code.cloudfoundry.org/dockerdriver/driverreconcile"
+	"code.cloudfoundry.org/dockerdriver/driverstate"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+	ginkgomon "github.com/tedsuo/ifrit/ginkgomon_v2"
+)
+
+var _ = Describe("Reconciler", func() {
+	const mountRoot = "/var/vcap/data/volumes/local"
+
+	var (
+		testLogger    *lagertest.TestLogger
+		store         *driverstate.FileStore
+		fakeMounter   *dockerdriverfakes.FakeMounter
+		fakeClock     *fakeclock.FakeClock
+		mountInfoPath string
+		config        driverreconcile.Config
+		reconciler    *driverreconcile.Reconciler
+	)
+
+	writeMountTable := func(mountpoints ...string) {
+		lines := []string{"22 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw"}
+		for i, mountpoint := range mountpoints {
+			lines = append(lines, fmt.Sprintf("%d 22 0:%d / %s rw - nfs4 server:/export rw", 40+i, 40+i, mountpoint))
+		}
+		Expect(os.WriteFile(mountInfoPath, []byte(strings.Join(lines, "\n")+"\n"), 0600)).To(Succeed())
+	}
+
+	mountCount := func(volume string) int {
+		record, err := store.Get(volume)
+		Expect(err).NotTo(HaveOccurred())
+		return record.MountCount
+	}
+
+	BeforeEach(func() {
+		testLogger = lagertest.NewTestLogger("reconciler-test")
+		fakeMounter = &dockerdriverfakes.FakeMounter{}
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+
+		dir := GinkgoT().TempDir()
+		mountInfoPath = filepath.Join(dir, "mountinfo")
+		writeMountTable()
+
+		var err error
+		store, err = driverstate.NewFileStore(testLogger, filepath.Join(dir, "volumes.json"))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(store.Create(driverstate.VolumeRecord{Name: "mounted", Mountpoint: mountRoot + "/mounted", MountCount: 2})).To(Succeed())
+		Expect(store.Create(driverstate.VolumeRecord{Name: "unmounted", Mountpoint: mountRoot + "/unmounted"})).To(Succeed())
+		Expect(store.Create(driverstate.VolumeRecord{Name: "never-mounted"})).To(Succeed())
+
+		config = driverreconcile.Config{
+			MountRoot:     mountRoot,
+			Policy:        driverreconcile.DefaultPolicy(),
+			MountInfoPath: mountInfoPath,
+		}
+	})
+
+	JustBeforeEach(func() {
+		reconciler = driverreconcile.NewReconciler(testLogger, store, fakeMounter, fakeClock, config)
+	})
+
+	It("should find nothing to do when the state matches the mount table", func() {
+		writeMountTable(mountRoot+"/mounted", "/some/other/mount")
+
+		results, err := reconciler.Reconcile(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(results).To(BeEmpty())
+		Expect(testLogger.Logs()).To(ContainElement(SatisfyAll(
+			HaveField("Message", HaveSuffix("summary")),
+			HaveField("Data", HaveKeyWithValue("problems", BeNumerically("==", 0))),
+		)))
+	})
+
+	Context("when a volume the state says is mounted has no mount", func() {
+		It("should mark it unmounted by default", func() {
+			results, err := reconciler.Reconcile(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(results).To(Equal([]driverreconcile.Result{{
+				Volume:     "mounted",
+				Mountpoint: mountRoot + "/mounted",
+				Problem:    driverreconcile.ProblemMissingMount,
+				Action:     driverreconcile.ActionCleanup,
+			}}))
+			Expect(mountCount("mounted")).To(Equal(0))
+			Expect(fakeMounter.RemountCallCount()).To(Equal(0))
+		})
+
+		Context("when the policy is to remount", func() {
+			BeforeEach(func() {
+				config.Policy.MissingMount = driverreconcile.ActionRemount
+			})
+
+			It("should remount it and keep its count", func() {
+				results, err := reconciler.Reconcile(context.Background())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(results).To(ConsistOf(HaveField("Action", driverreconcile.ActionRemount)))
+
+				Expect(fakeMounter.RemountCallCount()).To(Equal(1))
+				_, record := fakeMounter.RemountArgsForCall(0)
+				Expect(record.Name).To(Equal("mounted"))
+				Expect(mountCount("mounted")).To(Equal(2))
+			})
+
+			It("should report a failed remount and carry on", func() {
+				fakeMounter.RemountReturns(errors.New("mount.nfs: connection timed out"))
+
+				results, err := reconciler.Reconcile(context.Background())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(results).To(ConsistOf(HaveField("Err", MatchError("mount.nfs: connection timed out"))))
+				Expect(testLogger.Logs()).To(ContainElement(SatisfyAll(
+					HaveField("Message", HaveSuffix("failed-reconciling")),
+					HaveField("Data", HaveKeyWithValue("volume", "mounted")),
+				)))
+			})
+		})
+
+		Context("when the policy is empty", func() {
+			BeforeEach(func() {
+				config.Policy = driverreconcile.Policy{}
+			})
+
+			It("should only flag it", func() {
+				results, err := reconciler.Reconcile(context.Background())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(results).To(ConsistOf(HaveField("Action", driverreconcile.ActionFlag)))
+				Expect(mountCount("mounted")).To(Equal(2))
+			})
+		})
+	})
+
+	Context("when there are mounts under the mount root the state doesn't show", func() {
+		BeforeEach(func() {
+			writeMountTable(mountRoot+"/mounted", mountRoot+"/unmounted", mountRoot+"/stranger", "/elsewhere/stranger")
+		})
+
+		It("should adopt the ones that belong to a volume and flag the rest", func() {
+			results, err := reconciler.Reconcile(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(results).To(Equal([]driverreconcile.Result{
+				{Mountpoint: mountRoot + "/stranger", Problem: driverreconcile.ProblemUnknownMount, Action: driverreconcile.ActionFlag},
+				{Volume: "unmounted", Mountpoint: mountRoot + "/unmounted", Problem: driverreconcile.ProblemUnknownMount, Action: driverreconcile.ActionAdopt},
+			}))
+			Expect(mountCount("unmounted")).To(Equal(1))
+			Expect(fakeMounter.UnmountCallCount()).To(Equal(0))
+		})
+
+		Context("when the policy is to clean up", func() {
+			BeforeEach(func() {
+				config.Policy.UnknownMount = driverreconcile.ActionCleanup
+			})
+
+			It("should unmount them", func() {
+				_, err := reconciler.Reconcile(context.Background())
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeMounter.UnmountCallCount()).To(Equal(2))
+				_, first := fakeMounter.UnmountArgsForCall(0)
+				_, second := fakeMounter.UnmountArgsForCall(1)
+				Expect([]string{first, second}).To(ConsistOf(mountRoot+"/stranger", mountRoot+"/unmounted"))
+				Expect(mountCount("unmounted")).To(Equal(0))
+			})
+		})
+	})
+
+	It("should fail when the mount table can't be read", func() {
+		Expect(os.Remove(mountInfoPath)).To(Succeed())
+
+		_, err := reconciler.Reconcile(context.Background())
+		Expect(err).To(HaveOccurred())
+	})
+
+	Describe("Run", func() {
+		var process ifrit.Process
+
+		AfterEach(func() {
+			if process != nil {
+				ginkgomon.Interrupt(process)
+			}
+		})
+
+		It("should reconcile before it is ready", func() {
+			process = ginkgomon.Invoke(reconciler)
+			Expect(mountCount("mounted")).To(Equal(0))
+		})
+
+		It("should fail to start when it can't reconcile", func() {
+			Expect(os.Remove(mountInfoPath)).To(Succeed())
+
+			process = ifrit.Background(reconciler)
+			Eventually(process.Wait()).Should(Receive(HaveOccurred()))
+			process = nil
+		})
+
+		Context("with an interval", func() {
+			BeforeEach(func() {
+				config.Interval = time.Minute
+			})
+
+			It("should reconcile again on each tick", func() {
+				writeMountTable(mountRoot + "/mounted")
+				process = ginkgomon.Invoke(reconciler)
+				Expect(mountCount("mounted")).To(Equal(2))
+
+				writeMountTable()
+				Eventually(func() int {
+					fakeClock.WaitForWatcherAndIncrement(time.Minute)
+					return mountCount("mounted")
+				}).Should(Equal(0))
+			})
+		})
+	})
+})
@@ -0,0 +1,198 @@
+// Package driverrefcount shares one mount of a volume between everyone who
+// mounts it, so drivers don't each have to count references themselves.
+package driverrefcount
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/lager/v3"
+)
+
+type refCountingDriver struct {
+	driver dockerdriver.Driver
+
+	mu      sync.Mutex
+	volumes map[string]*volumeMounts
+}
+
+// volumeMounts counts the references to one volume by caller ID; mounts
+// without an ID share the "" entry. mu is held across calls to the driver, so
+// the first mount and last unmount of a volume can't race.
+type volumeMounts struct {
+	mu         sync.Mutex
+	mountpoint string
+	// mountID is the ID the driver was mounted with; the driver is unmounted
+	// with it too, whoever unmounts last.
+	mountID string
+	refs    map[string]int
+	users   int
+}
+
+func (v *volumeMounts) count() int {
+	count := 0
+	for _, refs := range v.refs {
+		count += refs
+	}
+	return count
+}
+
+// NewRefCountingDriver wraps driver so that its Mount is only called for the
+// first reference to a volume and its Unmount only for the last. Get and List
+// report the number of references in MountCount, and volumes that are still
+// mounted can't be removed.
+//
+// Unmounting a volume this driver has no record of, e.g. one mounted before a
+// restart, is passed straight to driver.
+func NewRefCountingDriver(driver dockerdriver.Driver) dockerdriver.Driver {
+	return &refCountingDriver{driver: driver, volumes: map[string]*volumeMounts{}}
+}
+
+// lock returns the volume's record, locked, and the func that unlocks it.
+func (d *refCountingDriver) lock(volume string) (*volumeMounts, func()) {
+	d.mu.Lock()
+	mounts, ok := d.volumes[volume]
+	if !ok {
+		mounts = &volumeMounts{refs: map[string]int{}}
+		d.volumes[volume] = mounts
+	}
+	mounts.users++
+	d.mu.Unlock()
+
+	mounts.mu.Lock()
+	return mounts, func() {
+		mounts.mu.Unlock()
+
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		mounts.users--
+		if mounts.users == 0 && mounts.count() == 0 {
+			delete(d.volumes, volume)
+		}
+	}
+}
+
+func (d *refCountingDriver) mountCount(volume string) int {
+	d.mu.Lock()
+	mounts, ok := d.volumes[volume]
+	d.mu.Unlock()
+	if !ok {
+		return 0
+	}
+
+	mounts.mu.Lock()
+	defer mounts.mu.Unlock()
+	return mounts.count()
+}
+
+func (d *refCountingDriver) Mount(env dockerdriver.Env, mountRequest dockerdriver.MountRequest) dockerdriver.MountResponse {
+	logger := env.Logger().Session("refcount-mount", lager.Data{"volume": mountRequest.Name, "id": mountRequest.ID})
+
+	mounts, unlock := d.lock(mountRequest.Name)
+	defer unlock()
+
+	if mounts.count() > 0 {
+		mounts.refs[mountRequest.ID]++
+		logger.Info("reusing-mount", lager.Data{"mount-count": mounts.count()})
+		return dockerdriver.MountResponse{Mountpoint: mounts.mountpoint}
+	}
+
+	mountResponse := d.driver.Mount(env, mountRequest)
+	if mountResponse.Err != "" {
+		return mountResponse
+	}
+
+	mounts.mountpoint = mountResponse.Mountpoint
+	mounts.mountID = mountRequest.ID
+	mounts.refs[mountRequest.ID]++
+	return mountResponse
+}
+
+func (d *refCountingDriver) Unmount(env dockerdriver.Env, unmountRequest dockerdriver.UnmountRequest) dockerdriver.ErrorResponse {
+	logger := env.Logger().Session("refcount-unmount", lager.Data{"volume": unmountRequest.Name, "id": unmountRequest.ID})
+
+	mounts, unlock := d.lock(unmountRequest.Name)
+	defer unlock()
+
+	if mounts.count() == 0 {
+		logger.Info("unmounting-unknown-mount")
+		return d.driver.Unmount(env, unmountRequest)
+	}
+
+	if mounts.refs[unmountRequest.ID] == 0 {
+		err := fmt.Errorf("volume %s is not mounted by %q", unmountRequest.Name, unmountRequest.ID)
+		logger.Error("failed-unmounting-volume", err)
+		return dockerdriver.ErrorResponse{Err: err.Error(), ErrCode: dockerdriver.ErrCodeNotFound}
+	}
+
+	if mounts.count() > 1 {
+		release(mounts, unmountRequest.ID)
+		logger.Info("keeping-mount", lager.Data{"mount-count": mounts.count()})
+		return dockerdriver.ErrorResponse{}
+	}
+
+	unmountResponse := d.driver.Unmount(env, dockerdriver.UnmountRequest{Name: unmountRequest.Name, ID: mounts.mountID})
+	if unmountResponse.Err != "" {
+		logger.Error("failed-unmounting-volume", errors.New(unmountResponse.Err))
+		return unmountResponse
+	}
+
+	release(mounts, unmountRequest.ID)
+	mounts.mountpoint = ""
+	mounts.mountID = ""
+	return unmountResponse
+}
+
+func release(mounts *volumeMounts, id string) {
+	mounts.refs[id]--
+	if mounts.refs[id] == 0 {
+		delete(mounts.refs, id)
+	}
+}
+
+func (d *refCountingDriver) Remove(env dockerdriver.Env, removeRequest dockerdriver.RemoveRequest) dockerdriver.ErrorResponse {
+	mounts, unlock := d.lock(removeRequest.Name)
+	defer unlock()
+
+	if count := mounts.count(); count > 0 {
+		return dockerdriver.ErrorResponse{
+			Err:     fmt.Sprintf("volume %s is still mounted %d times", removeRequest.Name, count),
+			ErrCode: dockerdriver.ErrCodeInUse,
+		}
+	}
+	return d.driver.Remove(env, removeRequest)
+}
+
+func (d *refCountingDriver) Get(env dockerdriver.Env, getRequest dockerdriver.GetRequest) dockerdriver.GetResponse {
+	getResponse := d.driver.Get(env, getRequest)
+	if getResponse.Err == "" {
+		getResponse.Volume.MountCount = d.mountCount(getRequest.Name)
+	}
+	return getResponse
+}
+
+func (d *refCountingDriver) List(env dockerdriver.Env) dockerdriver.ListResponse {
+	listResponse := d.driver.List(env)
+	for i := range listResponse.Volumes {
+		listResponse.Volumes[i].MountCount = d.mountCount(listResponse.Volumes[i].Name)
+	}
+	return listResponse
+}
+
+func (d *refCountingDriver) Activate(env dockerdriver.Env) dockerdriver.ActivateResponse {
+	return d.driver.Activate(env)
+}
+
+func (d *refCountingDriver) Create(env dockerdriver.Env, createRequest dockerdriver.CreateRequest) dockerdriver.ErrorResponse {
+	return d.driver.Create(env, createRequest)
+}
+
+func (d *refCountingDriver) Path(env dockerdriver.Env, pathRequest dockerdriver.PathRequest) dockerdriver.PathResponse {
+	return d.driver.Path(env, pathRequest)
+}
+
+func (d *refCountingDriver) Capabilities(env dockerdriver.Env) dockerdriver.CapabilitiesResponse {
+	return d.driver.Capabilities(env)
+}
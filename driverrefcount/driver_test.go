@@ -0,0 +1,193 @@
+package driverrefcount_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/dockerdriverfakes"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/dockerdriver/driverrefcount"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RefCountingDriver", func() {
+	var (
+		env        dockerdriver.Env
+		fakeDriver *dockerdriverfakes.FakeDriver
+		subject    dockerdriver.Driver
+	)
+
+	BeforeEach(func() {
+		env = driverhttp.NewHttpDriverEnv(lagertest.NewTestLogger("driverrefcount-test"), context.Background())
+
+		fakeDriver = &dockerdriverfakes.FakeDriver{}
+		fakeDriver.MountStub = func(_ dockerdriver.Env, mountRequest dockerdriver.MountRequest) dockerdriver.MountResponse {
+			return dockerdriver.MountResponse{Mountpoint: "/mnt/" + mountRequest.Name}
+		}
+		fakeDriver.GetStub = func(_ dockerdriver.Env, getRequest dockerdriver.GetRequest) dockerdriver.GetResponse {
+			return dockerdriver.GetResponse{Volume: dockerdriver.VolumeInfo{Name: getRequest.Name}}
+		}
+
+		subject = driverrefcount.NewRefCountingDriver(fakeDriver)
+	})
+
+	mount := func(volume, id string) dockerdriver.MountResponse {
+		return subject.Mount(env, dockerdriver.MountRequest{Name: volume, ID: id})
+	}
+
+	unmount := func(volume, id string) dockerdriver.ErrorResponse {
+		return subject.Unmount(env, dockerdriver.UnmountRequest{Name: volume, ID: id})
+	}
+
+	mountCount := func(volume string) int {
+		return subject.Get(env, dockerdriver.GetRequest{Name: volume}).Volume.MountCount
+	}
+
+	It("should only mount on the first reference", func() {
+		Expect(mount("some-volume", "container-1").Mountpoint).To(Equal("/mnt/some-volume"))
+		Expect(mount("some-volume", "container-2").Mountpoint).To(Equal("/mnt/some-volume"))
+
+		Expect(fakeDriver.MountCallCount()).To(Equal(1))
+		Expect(mountCount("some-volume")).To(Equal(2))
+	})
+
+	It("should only unmount on the last reference", func() {
+		mount("some-volume", "container-1")
+		mount("some-volume", "container-2")
+
+		Expect(unmount("some-volume", "container-1").Err).To(BeEmpty())
+		Expect(fakeDriver.UnmountCallCount()).To(Equal(0))
+		Expect(mountCount("some-volume")).To(Equal(1))
+
+		Expect(unmount("some-volume", "container-2").Err).To(BeEmpty())
+		Expect(fakeDriver.UnmountCallCount()).To(Equal(1))
+		Expect(mountCount("some-volume")).To(Equal(0))
+
+		By("mounting again after the last unmount")
+		mount("some-volume", "container-3")
+		Expect(fakeDriver.MountCallCount()).To(Equal(2))
+	})
+
+	It("should unmount the driver with the ID it was mounted with", func() {
+		mount("some-volume", "container-1")
+		mount("some-volume", "container-2")
+
+		unmount("some-volume", "container-1")
+		unmount("some-volume", "container-2")
+
+		Expect(fakeDriver.UnmountCallCount()).To(Equal(1))
+		_, unmountRequest := fakeDriver.UnmountArgsForCall(0)
+		Expect(unmountRequest).To(Equal(dockerdriver.UnmountRequest{Name: "some-volume", ID: "container-1"}))
+	})
+
+	It("should count repeated mounts by the same caller", func() {
+		mount("some-volume", "")
+		mount("some-volume", "")
+		unmount("some-volume", "")
+
+		Expect(fakeDriver.UnmountCallCount()).To(Equal(0))
+		Expect(mountCount("some-volume")).To(Equal(1))
+	})
+
+	It("should count each volume separately", func() {
+		mount("some-volume", "container-1")
+		mount("other-volume", "container-1")
+
+		Expect(fakeDriver.MountCallCount()).To(Equal(2))
+		Expect(mountCount("some-volume")).To(Equal(1))
+		Expect(mountCount("other-volume")).To(Equal(1))
+	})
+
+	It("should refuse to unmount for a caller that hasn't mounted the volume", func() {
+		mount("some-volume", "container-1")
+
+		response := unmount("some-volume", "container-2")
+		Expect(response.ErrCode).To(Equal(dockerdriver.ErrCodeNotFound))
+		Expect(fakeDriver.UnmountCallCount()).To(Equal(0))
+		Expect(mountCount("some-volume")).To(Equal(1))
+	})
+
+	It("should pass unmounts of volumes it hasn't seen mounted to the driver", func() {
+		Expect(unmount("some-volume", "container-1").Err).To(BeEmpty())
+		Expect(fakeDriver.UnmountCallCount()).To(Equal(1))
+	})
+
+	Context("when the driver fails to mount", func() {
+		BeforeEach(func() {
+			fakeDriver.MountStub = nil
+			fakeDriver.MountReturns(dockerdriver.MountResponse{Err: "badness"})
+		})
+
+		It("should not count the reference", func() {
+			Expect(mount("some-volume", "container-1").Err).To(Equal("badness"))
+			Expect(mountCount("some-volume")).To(Equal(0))
+		})
+	})
+
+	Context("when the driver fails to unmount", func() {
+		BeforeEach(func() {
+			fakeDriver.UnmountReturns(dockerdriver.ErrorResponse{Err: "device busy"})
+		})
+
+		It("should keep the reference so the unmount can be retried", func() {
+			mount("some-volume", "container-1")
+
+			Expect(unmount("some-volume", "container-1").Err).To(Equal("device busy"))
+			Expect(mountCount("some-volume")).To(Equal(1))
+
+			fakeDriver.UnmountReturns(dockerdriver.ErrorResponse{})
+			Expect(unmount("some-volume", "container-1").Err).To(BeEmpty())
+			Expect(mountCount("some-volume")).To(Equal(0))
+		})
+	})
+
+	It("should refuse to remove a mounted volume", func() {
+		mount("some-volume", "container-1")
+
+		response := subject.Remove(env, dockerdriver.RemoveRequest{Name: "some-volume"})
+		Expect(response.ErrCode).To(Equal(dockerdriver.ErrCodeInUse))
+		Expect(fakeDriver.RemoveCallCount()).To(Equal(0))
+
+		unmount("some-volume", "container-1")
+		Expect(subject.Remove(env, dockerdriver.RemoveRequest{Name: "some-volume"}).Err).To(BeEmpty())
+		Expect(fakeDriver.RemoveCallCount()).To(Equal(1))
+	})
+
+	It("should fill in the mount counts of listed volumes", func() {
+		fakeDriver.ListReturns(dockerdriver.ListResponse{Volumes: []dockerdriver.VolumeInfo{
+			{Name: "some-volume", MountCount: 7},
+			{Name: "other-volume"},
+		}})
+		mount("some-volume", "container-1")
+		mount("some-volume", "container-2")
+
+		Expect(subject.List(env).Volumes).To(ConsistOf(
+			HaveField("MountCount", 2),
+			HaveField("MountCount", 0),
+		))
+	})
+
+	It("should keep an exact count under concurrent mounts and unmounts", func() {
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				id := fmt.Sprintf("container-%d", i)
+				Expect(mount("some-volume", id).Err).To(BeEmpty())
+				if i >= 10 {
+					Expect(unmount("some-volume", id).Err).To(BeEmpty())
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		Expect(mountCount("some-volume")).To(Equal(10))
+		Expect(fakeDriver.MountCallCount() - fakeDriver.UnmountCallCount()).To(Equal(1))
+	})
+})
@@ -0,0 +1,13 @@
+package driverrefcount_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestDriverRefCount(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Driver Ref Count Suite")
+}
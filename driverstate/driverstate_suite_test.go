@@ -0,0 +1,13 @@
+package driverstate_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestDriverState(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Driver State Suite")
+}
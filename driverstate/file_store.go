@@ -0,0 +1,265 @@
+package driverstate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"code.cloudfoundry.org/lager/v3"
+)
+
+const stateFileVersion = 1
+
+// stateFile is the format on disk. Checksum covers Volumes, so a file that
+// was only partly written is noticed rather than read as fewer volumes.
+type stateFile struct {
+	Version  int
+	Checksum string
+	Volumes  json.RawMessage
+}
+
+// FileStore keeps the records in memory and writes them all to one JSON file
+// on every change. Each write goes to a temporary file that is synced and
+// then renamed over the old one, whose previous contents are kept as a
+// backup, so there is always a complete generation on disk to load.
+type FileStore struct {
+	logger lager.Logger
+	path   string
+
+	lock    sync.RWMutex
+	volumes map[string]VolumeRecord
+	// backupIsNewest is set when the file could not be read and the records
+	// came from the backup, which must then not be overwritten by the file.
+	backupIsNewest bool
+}
+
+var _ VolumeStore = (*FileStore)(nil)
+
+// NewFileStore loads the records in path, falling back to its backup if path
+// is missing or damaged. Neither existing is an empty store; neither being
+// readable is an error.
+func NewFileStore(logger lager.Logger, path string) (*FileStore, error) {
+	logger = logger.Session("file-store", lager.Data{"path": path})
+
+	s := &FileStore{logger: logger, path: path}
+
+	volumes, err := readStateFile(path)
+	if err == nil {
+		s.volumes = volumes
+		return s, nil
+	}
+
+	volumes, backupErr := readStateFile(s.backupPath())
+	switch {
+	case backupErr == nil:
+		logger.Error("recovered-from-backup", err)
+		s.volumes = volumes
+		s.backupIsNewest = true
+		return s, nil
+	case errors.Is(err, fs.ErrNotExist) && errors.Is(backupErr, fs.ErrNotExist):
+		s.volumes = map[string]VolumeRecord{}
+		return s, nil
+	case errors.Is(err, fs.ErrNotExist):
+		return nil, fmt.Errorf("reading volume state backup: %w", backupErr)
+	default:
+		return nil, fmt.Errorf("reading volume state: %w", err)
+	}
+}
+
+func (s *FileStore) backupPath() string {
+	return s.path + ".bak"
+}
+
+func (s *FileStore) Create(record VolumeRecord) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.volumes[record.Name]; ok {
+		return alreadyExists(record.Name)
+	}
+
+	volumes := maps.Clone(s.volumes)
+	volumes[record.Name] = record.clone()
+	return s.save(volumes)
+}
+
+func (s *FileStore) Get(name string) (VolumeRecord, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	record, ok := s.volumes[name]
+	if !ok {
+		return VolumeRecord{}, notFound(name)
+	}
+	return record.clone(), nil
+}
+
+func (s *FileStore) List() ([]VolumeRecord, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return sortedRecords(s.volumes), nil
+}
+
+func (s *FileStore) Update(name string, update func(*VolumeRecord) error) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	record, ok := s.volumes[name]
+	if !ok {
+		return notFound(name)
+	}
+
+	record = record.clone()
+	if err := update(&record); err != nil {
+		return err
+	}
+	record.Name = name
+
+	volumes := maps.Clone(s.volumes)
+	volumes[name] = record
+	return s.save(volumes)
+}
+
+func (s *FileStore) Delete(name string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.volumes[name]; !ok {
+		return notFound(name)
+	}
+
+	volumes := maps.Clone(s.volumes)
+	delete(volumes, name)
+	return s.save(volumes)
+}
+
+// save writes volumes to disk and only then makes them the store's records,
+// so a failed write leaves the store as it was.
+func (s *FileStore) save(volumes map[string]VolumeRecord) error {
+	data, err := encodeStateFile(volumes)
+	if err != nil {
+		return err
+	}
+
+	tempPath := s.path + ".tmp"
+	if err := writeFileSynced(tempPath, data); err != nil {
+		s.logger.Error("failed-writing-volume-state", err)
+		return err
+	}
+
+	if s.backupIsNewest {
+		err = os.Remove(s.path)
+	} else {
+		err = os.Rename(s.path, s.backupPath())
+	}
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		s.logger.Error("failed-keeping-backup", err)
+		return err
+	}
+
+	if err := os.Rename(tempPath, s.path); err != nil {
+		s.logger.Error("failed-replacing-volume-state", err)
+		return err
+	}
+	if err := syncDir(filepath.Dir(s.path)); err != nil {
+		s.logger.Error("failed-syncing-volume-state-dir", err)
+		return err
+	}
+
+	s.volumes = volumes
+	s.backupIsNewest = false
+	return nil
+}
+
+func sortedRecords(volumes map[string]VolumeRecord) []VolumeRecord {
+	records := make([]VolumeRecord, 0, len(volumes))
+	for _, record := range volumes {
+		records = append(records, record.clone())
+	}
+	slices.SortFunc(records, func(a, b VolumeRecord) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return records
+}
+
+func encodeStateFile(volumes map[string]VolumeRecord) ([]byte, error) {
+	records, err := json.Marshal(sortedRecords(volumes))
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(stateFile{
+		Version:  stateFileVersion,
+		Checksum: checksum(records),
+		Volumes:  records,
+	})
+}
+
+func readStateFile(path string) (map[string]VolumeRecord, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file stateFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%s is damaged: %w", path, err)
+	}
+	if file.Version != stateFileVersion {
+		return nil, fmt.Errorf("%s has unsupported version %d", path, file.Version)
+	}
+	if checksum(file.Volumes) != file.Checksum {
+		return nil, fmt.Errorf("%s is damaged: checksum mismatch", path)
+	}
+
+	var records []VolumeRecord
+	if err := json.Unmarshal(file.Volumes, &records); err != nil {
+		return nil, fmt.Errorf("%s is damaged: %w", path, err)
+	}
+
+	volumes := make(map[string]VolumeRecord, len(records))
+	for _, record := range records {
+		volumes[record.Name] = record
+	}
+	return volumes, nil
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func writeFileSynced(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
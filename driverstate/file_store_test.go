@@ -0,0 +1,205 @@
+package driverstate_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverstate"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FileStore", func() {
+	var (
+		testLogger *lagertest.TestLogger
+		path       string
+		store      *driverstate.FileStore
+	)
+
+	open := func() *driverstate.FileStore {
+		store, err := driverstate.NewFileStore(testLogger, path)
+		Expect(err).NotTo(HaveOccurred())
+		return store
+	}
+
+	BeforeEach(func() {
+		testLogger = lagertest.NewTestLogger("driverstate-test")
+		path = filepath.Join(GinkgoT().TempDir(), "volumes.json")
+		store = open()
+	})
+
+	It("should start empty when there is no state", func() {
+		Expect(store.List()).To(BeEmpty())
+	})
+
+	It("should create, get, update and delete records", func() {
+		record := driverstate.NewVolumeRecord(dockerdriver.CreateRequest{Name: "some-volume", Opts: map[string]interface{}{"source": "nfs://server/export"}})
+		Expect(store.Create(record)).To(Succeed())
+
+		Expect(store.Get("some-volume")).To(Equal(record))
+
+		Expect(store.Update("some-volume", func(record *driverstate.VolumeRecord) error {
+			record.Mountpoint = "/mnt/some-volume"
+			record.MountCount++
+			return nil
+		})).To(Succeed())
+
+		updated, err := store.Get("some-volume")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(updated.VolumeInfo()).To(Equal(dockerdriver.VolumeInfo{Name: "some-volume", Mountpoint: "/mnt/some-volume", MountCount: 1}))
+		Expect(updated.Opts).To(HaveKeyWithValue("source", "nfs://server/export"))
+
+		Expect(store.Delete("some-volume")).To(Succeed())
+		_, err = store.Get("some-volume")
+		Expect(err).To(MatchError(dockerdriver.ErrNotFound))
+	})
+
+	It("should report missing and duplicate records with dockerdriver's errors", func() {
+		Expect(store.Create(driverstate.VolumeRecord{Name: "some-volume"})).To(Succeed())
+		Expect(store.Create(driverstate.VolumeRecord{Name: "some-volume"})).To(MatchError(dockerdriver.ErrAlreadyExists))
+
+		Expect(store.Delete("other-volume")).To(MatchError(dockerdriver.ErrNotFound))
+		Expect(store.Update("other-volume", func(*driverstate.VolumeRecord) error { return nil })).To(MatchError(dockerdriver.ErrNotFound))
+	})
+
+	It("should list records sorted by name", func() {
+		for _, name := range []string{"c", "a", "b"} {
+			Expect(store.Create(driverstate.VolumeRecord{Name: name})).To(Succeed())
+		}
+
+		Expect(store.List()).To(HaveExactElements(
+			HaveField("Name", "a"),
+			HaveField("Name", "b"),
+			HaveField("Name", "c"),
+		))
+	})
+
+	It("should keep changes only when the update succeeds", func() {
+		Expect(store.Create(driverstate.VolumeRecord{Name: "some-volume"})).To(Succeed())
+
+		err := store.Update("some-volume", func(record *driverstate.VolumeRecord) error {
+			record.MountCount = 5
+			return fmt.Errorf("badness")
+		})
+		Expect(err).To(MatchError("badness"))
+
+		Expect(store.Get("some-volume")).To(HaveField("MountCount", 0))
+	})
+
+	It("should not let callers change stored records", func() {
+		Expect(store.Create(driverstate.VolumeRecord{Name: "some-volume", Opts: map[string]interface{}{"a": "1"}})).To(Succeed())
+
+		record, err := store.Get("some-volume")
+		Expect(err).NotTo(HaveOccurred())
+		record.Opts["a"] = "2"
+
+		Expect(store.Get("some-volume")).To(HaveField("Opts", HaveKeyWithValue("a", "1")))
+	})
+
+	It("should load what was saved when reopened", func() {
+		Expect(store.Create(driverstate.VolumeRecord{Name: "some-volume", Opts: map[string]interface{}{"uid": "1000"}, MountCount: 2})).To(Succeed())
+		Expect(store.Create(driverstate.VolumeRecord{Name: "other-volume"})).To(Succeed())
+
+		Expect(open().List()).To(Equal([]driverstate.VolumeRecord{
+			{Name: "other-volume"},
+			{Name: "some-volume", Opts: map[string]interface{}{"uid": "1000"}, MountCount: 2},
+		}))
+	})
+
+	It("should keep the previous generation as a backup", func() {
+		Expect(store.Create(driverstate.VolumeRecord{Name: "some-volume"})).To(Succeed())
+		Expect(store.Create(driverstate.VolumeRecord{Name: "other-volume"})).To(Succeed())
+
+		Expect(path + ".bak").To(BeARegularFile())
+		Expect(path + ".tmp").NotTo(BeAnExistingFile())
+
+		Expect(os.Rename(path+".bak", path)).To(Succeed())
+		Expect(open().List()).To(ConsistOf(HaveField("Name", "some-volume")))
+	})
+
+	Context("when the state file is damaged", func() {
+		BeforeEach(func() {
+			Expect(store.Create(driverstate.VolumeRecord{Name: "some-volume"})).To(Succeed())
+			Expect(store.Create(driverstate.VolumeRecord{Name: "other-volume"})).To(Succeed())
+		})
+
+		for _, damage := range []struct {
+			description string
+			apply       func()
+		}{
+			{"torn", func() {
+				data, err := os.ReadFile(path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(os.WriteFile(path, data[:len(data)/2], 0600)).To(Succeed())
+			}},
+			{"corrupted", func() {
+				data, err := os.ReadFile(path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(os.WriteFile(path, bytes.Replace(data, []byte("other-volume"), []byte("other-volumx"), 1), 0600)).To(Succeed())
+			}},
+			{"missing", func() {
+				Expect(os.Remove(path)).To(Succeed())
+			}},
+		} {
+			It("should recover from the backup when it is "+damage.description, func() {
+				damage.apply()
+
+				recovered := open()
+				Expect(recovered.List()).To(ConsistOf(HaveField("Name", "some-volume")))
+
+				By("not overwriting the backup with the damaged file on the next write")
+				Expect(recovered.Create(driverstate.VolumeRecord{Name: "third-volume"})).To(Succeed())
+				Expect(os.Rename(path+".bak", path)).To(Succeed())
+				Expect(open().List()).To(ConsistOf(HaveField("Name", "some-volume")))
+			})
+		}
+
+		It("should fail to open when the backup is damaged too", func() {
+			Expect(os.WriteFile(path, []byte(`{"Version":1`), 0600)).To(Succeed())
+			Expect(os.WriteFile(path+".bak", []byte(`{"Version":1`), 0600)).To(Succeed())
+
+			_, err := driverstate.NewFileStore(testLogger, path)
+			Expect(err).To(MatchError(ContainSubstring("damaged")))
+		})
+	})
+
+	It("should ignore a temporary file left by an interrupted write", func() {
+		Expect(store.Create(driverstate.VolumeRecord{Name: "some-volume"})).To(Succeed())
+		Expect(os.WriteFile(path+".tmp", []byte(`{"Vers`), 0600)).To(Succeed())
+
+		reopened := open()
+		Expect(reopened.List()).To(HaveLen(1))
+		Expect(reopened.Create(driverstate.VolumeRecord{Name: "other-volume"})).To(Succeed())
+		Expect(open().List()).To(HaveLen(2))
+	})
+
+	It("should be safe to use from many goroutines", func() {
+		Expect(store.Create(driverstate.VolumeRecord{Name: "shared"})).To(Succeed())
+
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				Expect(store.Create(driverstate.VolumeRecord{Name: fmt.Sprintf("volume-%d", i)})).To(Succeed())
+				Expect(store.Update("shared", func(record *driverstate.VolumeRecord) error {
+					record.MountCount++
+					return nil
+				})).To(Succeed())
+				_, err := store.List()
+				Expect(err).NotTo(HaveOccurred())
+			}(i)
+		}
+		wg.Wait()
+
+		reopened := open()
+		Expect(reopened.List()).To(HaveLen(21))
+		Expect(reopened.Get("shared")).To(HaveField("MountCount", 20))
+	})
+})
@@ -0,0 +1,58 @@
+// Package driverstate persists what a driver knows about its volumes across
+// restarts.
+package driverstate
+
+import (
+	"fmt"
+	"maps"
+
+	"code.cloudfoundry.org/dockerdriver"
+)
+
+// VolumeRecord is what a driver remembers about one volume.
+type VolumeRecord struct {
+	Name       string
+	Opts       map[string]interface{} `json:",omitempty"`
+	Mountpoint string                 `json:",omitempty"`
+	MountCount int                    `json:",omitempty"`
+}
+
+func NewVolumeRecord(createRequest dockerdriver.CreateRequest) VolumeRecord {
+	return VolumeRecord{Name: createRequest.Name, Opts: createRequest.Opts}.clone()
+}
+
+func (r VolumeRecord) VolumeInfo() dockerdriver.VolumeInfo {
+	return dockerdriver.VolumeInfo{Name: r.Name, Mountpoint: r.Mountpoint, MountCount: r.MountCount}
+}
+
+// clone copies Opts, so that a record handed out can't change the stored one.
+// Values inside Opts are shared.
+func (r VolumeRecord) clone() VolumeRecord {
+	r.Opts = maps.Clone(r.Opts)
+	return r
+}
+
+//go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -generate
+//counterfeiter:generate -o ../dockerdriverfakes/fake_volume_store.go . VolumeStore
+
+// VolumeStore is safe to use from many goroutines. Records that aren't there
+// are reported with dockerdriver.ErrNotFound, and creating one twice with
+// dockerdriver.ErrAlreadyExists.
+type VolumeStore interface {
+	Create(record VolumeRecord) error
+	Get(name string) (VolumeRecord, error)
+	// List returns the records sorted by name.
+	List() ([]VolumeRecord, error)
+	// Update applies update to the named record and saves the result, unless
+	// update returns an error.
+	Update(name string, update func(*VolumeRecord) error) error
+	Delete(name string) error
+}
+
+func notFound(name string) error {
+	return dockerdriver.NewDriverError(dockerdriver.ErrCodeNotFound, fmt.Sprintf("volume %s not found", name))
+}
+
+func alreadyExists(name string) error {
+	return dockerdriver.NewDriverError(dockerdriver.ErrCodeAlreadyExists, fmt.Sprintf("volume %s already exists", name))
+}
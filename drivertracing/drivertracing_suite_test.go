@@ -0,0 +1,13 @@
+package drivertracing_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestDriverTracing(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Driver Tracing Suite")
+}
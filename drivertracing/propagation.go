@@ -0,0 +1,141 @@
+package drivertracing
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// Format selects the headers Inject writes. Extract understands them all.
+type Format int
+
+const (
+	// FormatW3C is the W3C Trace Context traceparent header.
+	FormatW3C Format = 1 << iota
+	// FormatB3 is Zipkin's multi-header B3 format.
+	FormatB3
+)
+
+const (
+	TraceparentHeader = "traceparent"
+	B3Header          = "b3"
+	B3TraceIDHeader   = "X-B3-TraceId"
+	B3SpanIDHeader    = "X-B3-SpanId"
+	B3SampledHeader   = "X-B3-Sampled"
+	B3FlagsHeader     = "X-B3-Flags"
+)
+
+// Inject writes the span in ctx, if any, into header.
+func Inject(ctx context.Context, header http.Header, formats Format) {
+	spanContext, ok := SpanContextFromContext(ctx)
+	if !ok {
+		return
+	}
+
+	if formats&FormatW3C != 0 {
+		flags := "00"
+		if spanContext.Sampled {
+			flags = "01"
+		}
+		header.Set(TraceparentHeader, "00-"+spanContext.TraceID.String()+"-"+spanContext.SpanID.String()+"-"+flags)
+	}
+
+	if formats&FormatB3 != 0 {
+		sampled := "0"
+		if spanContext.Sampled {
+			sampled = "1"
+		}
+		header.Set(B3TraceIDHeader, spanContext.TraceID.String())
+		header.Set(B3SpanIDHeader, spanContext.SpanID.String())
+		header.Set(B3SampledHeader, sampled)
+	}
+}
+
+// Extract reads a remote span context from traceparent, or failing that from
+// single or multi-header B3.
+func Extract(header http.Header) (SpanContext, bool) {
+	if traceparent := header.Get(TraceparentHeader); traceparent != "" {
+		if spanContext, ok := parseTraceparent(traceparent); ok {
+			return spanContext, true
+		}
+	}
+	if b3 := header.Get(B3Header); b3 != "" {
+		if spanContext, ok := parseB3Single(b3); ok {
+			return spanContext, true
+		}
+	}
+	if traceID := header.Get(B3TraceIDHeader); traceID != "" {
+		sampled := header.Get(B3SampledHeader)
+		debug := header.Get(B3FlagsHeader) == "1"
+		return parseB3(traceID, header.Get(B3SpanIDHeader), sampled == "" || sampled == "1" || sampled == "true" || debug)
+	}
+	return SpanContext{}, false
+}
+
+// ContextWithRemoteSpanContext returns ctx carrying the span extracted from
+// header, or ctx itself if header has none.
+func ContextWithRemoteSpanContext(ctx context.Context, header http.Header) context.Context {
+	spanContext, ok := Extract(header)
+	if !ok {
+		return ctx
+	}
+	return ContextWithSpanContext(ctx, spanContext)
+}
+
+func parseTraceparent(traceparent string) (SpanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return SpanContext{}, false
+	}
+
+	var spanContext SpanContext
+	if !decodeHex(parts[1], spanContext.TraceID[:]) || !decodeHex(parts[2], spanContext.SpanID[:]) {
+		return SpanContext{}, false
+	}
+
+	var flags [1]byte
+	if !decodeHex(parts[3], flags[:]) {
+		return SpanContext{}, false
+	}
+	spanContext.Sampled = flags[0]&0x01 != 0
+	spanContext.Remote = true
+
+	return spanContext, spanContext.IsValid()
+}
+
+// parseB3Single reads "{trace id}-{span id}[-{sampling}[-{parent span id}]]".
+func parseB3Single(b3 string) (SpanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(b3), "-")
+	if len(parts) < 2 {
+		return SpanContext{}, false
+	}
+
+	sampled := true
+	if len(parts) > 2 {
+		sampled = parts[2] == "1" || parts[2] == "d"
+	}
+	return parseB3(parts[0], parts[1], sampled)
+}
+
+// parseB3 allows 64 bit trace ids, which it pads to 128 bits.
+func parseB3(traceID, spanID string, sampled bool) (SpanContext, bool) {
+	if len(traceID) == 16 {
+		traceID = strings.Repeat("0", 16) + traceID
+	}
+
+	spanContext := SpanContext{Sampled: sampled, Remote: true}
+	if !decodeHex(traceID, spanContext.TraceID[:]) || !decodeHex(spanID, spanContext.SpanID[:]) {
+		return SpanContext{}, false
+	}
+	return spanContext, spanContext.IsValid()
+}
+
+// decodeHex only accepts lower case hex of exactly the right length.
+func decodeHex(s string, dst []byte) bool {
+	if len(s) != hex.EncodedLen(len(dst)) || strings.ToLower(s) != s {
+		return false
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err == nil
+}
@@ -0,0 +1,91 @@
+package drivertracing_test
+
+import (
+	"context"
+	"net/http"
+
+	"code.cloudfoundry.org/dockerdriver/drivertracing"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Propagation", func() {
+	var spanContext drivertracing.SpanContext
+
+	BeforeEach(func() {
+		spanContext = drivertracing.SpanContext{
+			TraceID: drivertracing.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+			SpanID:  drivertracing.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+			Sampled: true,
+		}
+	})
+
+	It("should inject a W3C traceparent", func() {
+		header := http.Header{}
+		drivertracing.Inject(drivertracing.ContextWithSpanContext(context.Background(), spanContext), header, drivertracing.FormatW3C)
+
+		Expect(header.Get("traceparent")).To(Equal("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"))
+		Expect(header.Get("X-B3-TraceId")).To(BeEmpty())
+	})
+
+	It("should inject B3 headers", func() {
+		header := http.Header{}
+		drivertracing.Inject(drivertracing.ContextWithSpanContext(context.Background(), spanContext), header, drivertracing.FormatW3C|drivertracing.FormatB3)
+
+		Expect(header.Get("X-B3-TraceId")).To(Equal("4bf92f3577b34da6a3ce929d0e0e4736"))
+		Expect(header.Get("X-B3-SpanId")).To(Equal("00f067aa0ba902b7"))
+		Expect(header.Get("X-B3-Sampled")).To(Equal("1"))
+		Expect(header.Get("traceparent")).NotTo(BeEmpty())
+	})
+
+	It("should inject nothing without a span", func() {
+		header := http.Header{}
+		drivertracing.Inject(context.Background(), header, drivertracing.FormatW3C|drivertracing.FormatB3)
+
+		Expect(header).To(BeEmpty())
+	})
+
+	DescribeTable("Extract",
+		func(headers map[string]string, sampled bool) {
+			header := http.Header{}
+			for key, value := range headers {
+				header.Set(key, value)
+			}
+
+			extracted, ok := drivertracing.Extract(header)
+			Expect(ok).To(BeTrue())
+			Expect(extracted.TraceID).To(Equal(spanContext.TraceID))
+			Expect(extracted.SpanID).To(Equal(spanContext.SpanID))
+			Expect(extracted.Sampled).To(Equal(sampled))
+			Expect(extracted.Remote).To(BeTrue())
+		},
+		Entry("traceparent", map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}, true),
+		Entry("unsampled traceparent", map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"}, false),
+		Entry("single b3", map[string]string{"b3": "4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-1"}, true),
+		Entry("multi b3", map[string]string{"X-B3-TraceId": "4bf92f3577b34da6a3ce929d0e0e4736", "X-B3-SpanId": "00f067aa0ba902b7", "X-B3-Sampled": "0"}, false),
+	)
+
+	It("should pad a 64 bit B3 trace id", func() {
+		header := http.Header{}
+		header.Set("b3", "a3ce929d0e0e4736-00f067aa0ba902b7")
+
+		extracted, ok := drivertracing.Extract(header)
+		Expect(ok).To(BeTrue())
+		Expect(extracted.TraceID.String()).To(Equal("0000000000000000a3ce929d0e0e4736"))
+	})
+
+	DescribeTable("rejecting invalid headers",
+		func(traceparent string) {
+			header := http.Header{}
+			header.Set("traceparent", traceparent)
+
+			_, ok := drivertracing.Extract(header)
+			Expect(ok).To(BeFalse())
+		},
+		Entry("zero trace id", "00-00000000000000000000000000000000-00f067aa0ba902b7-01"),
+		Entry("upper case", "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01"),
+		Entry("short span id", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa-01"),
+		Entry("forbidden version", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"),
+		Entry("garbage", "not a traceparent"),
+	)
+})
@@ -0,0 +1,182 @@
+// Package drivertracing carries trace context between the volume manager and
+// drivers, so a driver's work can be linked to the span that asked for it.
+// Spans are handed to a pluggable Exporter when they end.
+package drivertracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager/v3"
+)
+
+type TraceID [16]byte
+
+func (t TraceID) IsValid() bool {
+	return t != TraceID{}
+}
+
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+type SpanID [8]byte
+
+func (s SpanID) IsValid() bool {
+	return s != SpanID{}
+}
+
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// SpanContext is the part of a span that crosses process boundaries.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+	// Remote is set on span contexts extracted from a request.
+	Remote bool
+}
+
+func (s SpanContext) IsValid() bool {
+	return s.TraceID.IsValid() && s.SpanID.IsValid()
+}
+
+type spanContextKey struct{}
+
+func ContextWithSpanContext(ctx context.Context, spanContext SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, spanContext)
+}
+
+func SpanContextFromContext(ctx context.Context) (SpanContext, bool) {
+	spanContext, ok := ctx.Value(spanContextKey{}).(SpanContext)
+	return spanContext, ok && spanContext.IsValid()
+}
+
+// LogData is the trace and span id in ctx, for adding to lager sessions. It
+// is empty when ctx carries no span.
+func LogData(ctx context.Context) lager.Data {
+	spanContext, ok := SpanContextFromContext(ctx)
+	if !ok {
+		return lager.Data{}
+	}
+	return lager.Data{"trace-id": spanContext.TraceID.String(), "span-id": spanContext.SpanID.String()}
+}
+
+type SpanKind string
+
+const (
+	SpanKindClient SpanKind = "client"
+	SpanKindServer SpanKind = "server"
+)
+
+// SpanData is what an Exporter gets for each finished span.
+type SpanData struct {
+	Name         string
+	Kind         SpanKind
+	SpanContext  SpanContext
+	ParentSpanID SpanID
+	StartTime    time.Time
+	EndTime      time.Time
+	Attributes   map[string]string
+	Err          string
+}
+
+type Tracer struct {
+	exporter Exporter
+	clock    clock.Clock
+}
+
+func NewTracer(exporter Exporter, clock clock.Clock) *Tracer {
+	return &Tracer{exporter: exporter, clock: clock}
+}
+
+// Start begins a span that is a child of the span in ctx, or the root of a
+// new trace if there is none, and returns ctx carrying the new span.
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	data := SpanData{
+		Name:       name,
+		Kind:       kind,
+		StartTime:  t.clock.Now(),
+		Attributes: map[string]string{},
+	}
+
+	if parent, ok := SpanContextFromContext(ctx); ok {
+		data.SpanContext.TraceID = parent.TraceID
+		data.SpanContext.Sampled = parent.Sampled
+		data.ParentSpanID = parent.SpanID
+	} else {
+		data.SpanContext.TraceID = newTraceID()
+		data.SpanContext.Sampled = true
+	}
+	data.SpanContext.SpanID = newSpanID()
+
+	span := &Span{tracer: t, data: data}
+	return ContextWithSpanContext(ctx, data.SpanContext), span
+}
+
+type Span struct {
+	tracer *Tracer
+
+	lock  sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+func (s *Span) SpanContext() SpanContext {
+	return s.data.SpanContext
+}
+
+func (s *Span) SetAttribute(key, value string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.data.Attributes[key] = value
+}
+
+func (s *Span) SetError(err string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.data.Err = err
+}
+
+// End exports the span if it is sampled. Only the first call has any effect.
+func (s *Span) End() {
+	s.lock.Lock()
+	if s.ended {
+		s.lock.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.EndTime = s.tracer.clock.Now()
+	data := s.data
+	data.Attributes = make(map[string]string, len(s.data.Attributes))
+	for key, value := range s.data.Attributes {
+		data.Attributes[key] = value
+	}
+	s.lock.Unlock()
+
+	if data.SpanContext.Sampled && s.tracer.exporter != nil {
+		s.tracer.exporter.ExportSpan(data)
+	}
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		_, _ = rand.Read(id[:])
+	}
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		_, _ = rand.Read(id[:])
+	}
+	return id
+}
@@ -0,0 +1,74 @@
+package drivertracing_test
+
+import (
+	"context"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/dockerdriver/drivertracing"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tracer", func() {
+	var (
+		fakeClock *fakeclock.FakeClock
+		exporter  *drivertracing.InMemoryExporter
+		tracer    *drivertracing.Tracer
+	)
+
+	BeforeEach(func() {
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+		exporter = drivertracing.NewInMemoryExporter()
+		tracer = drivertracing.NewTracer(exporter, fakeClock)
+	})
+
+	It("should start a new sampled trace when the context has no span", func() {
+		ctx, span := tracer.Start(context.Background(), "mount", drivertracing.SpanKindClient)
+
+		spanContext, ok := drivertracing.SpanContextFromContext(ctx)
+		Expect(ok).To(BeTrue())
+		Expect(spanContext).To(Equal(span.SpanContext()))
+		Expect(spanContext.Sampled).To(BeTrue())
+	})
+
+	It("should export a child of the span in the context when it ends", func() {
+		ctx, parent := tracer.Start(context.Background(), "parent", drivertracing.SpanKindServer)
+		_, child := tracer.Start(ctx, "child", drivertracing.SpanKindClient)
+		child.SetAttribute("route", "mount")
+		child.SetError("boom")
+
+		fakeClock.Increment(time.Second)
+		child.End()
+		child.End()
+
+		spans := exporter.Spans()
+		Expect(spans).To(HaveLen(1))
+		Expect(spans[0].Name).To(Equal("child"))
+		Expect(spans[0].SpanContext.TraceID).To(Equal(parent.SpanContext().TraceID))
+		Expect(spans[0].ParentSpanID).To(Equal(parent.SpanContext().SpanID))
+		Expect(spans[0].EndTime.Sub(spans[0].StartTime)).To(Equal(time.Second))
+		Expect(spans[0].Attributes).To(Equal(map[string]string{"route": "mount"}))
+		Expect(spans[0].Err).To(Equal("boom"))
+	})
+
+	It("should not export spans of a trace that is not sampled", func() {
+		ctx := drivertracing.ContextWithSpanContext(context.Background(), drivertracing.SpanContext{
+			TraceID: drivertracing.TraceID{1},
+			SpanID:  drivertracing.SpanID{1},
+		})
+
+		_, span := tracer.Start(ctx, "mount", drivertracing.SpanKindServer)
+		span.End()
+
+		Expect(exporter.Spans()).To(BeEmpty())
+	})
+
+	It("should give log data for the span in the context", func() {
+		ctx, span := tracer.Start(context.Background(), "mount", drivertracing.SpanKindClient)
+
+		Expect(drivertracing.LogData(ctx)).To(HaveKeyWithValue("trace-id", span.SpanContext().TraceID.String()))
+		Expect(drivertracing.LogData(ctx)).To(HaveKeyWithValue("span-id", span.SpanContext().SpanID.String()))
+		Expect(drivertracing.LogData(context.Background())).To(BeEmpty())
+	})
+})
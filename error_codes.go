@@ -0,0 +1,150 @@
+package dockerdriver
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrorCode is a machine readable classification of a response's Err, sent
+// alongside it as ErrCode. Docker ignores the field, and drivers that don't
+// set it can still be classified with ClassifyError.
+type ErrorCode string
+
+const (
+	ErrCodeNotFound         ErrorCode = "not-found"
+	ErrCodeAlreadyExists    ErrorCode = "already-exists"
+	ErrCodeInUse            ErrorCode = "in-use"
+	ErrCodeInvalidOptions   ErrorCode = "invalid-options"
+	ErrCodePermissionDenied ErrorCode = "permission-denied"
+	ErrCodeTimeout          ErrorCode = "timeout"
+	ErrCodeUnavailable      ErrorCode = "unavailable"
+)
+
+var (
+	ErrNotFound         = errors.New("volume not found")
+	ErrAlreadyExists    = errors.New("volume already exists")
+	ErrInUse            = errors.New("volume in use")
+	ErrInvalidOptions   = errors.New("invalid volume options")
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrTimeout          = errors.New("timed out")
+	ErrUnavailable      = errors.New("driver unavailable")
+)
+
+var errorsByCode = map[ErrorCode]error{
+	ErrCodeNotFound:         ErrNotFound,
+	ErrCodeAlreadyExists:    ErrAlreadyExists,
+	ErrCodeInUse:            ErrInUse,
+	ErrCodeInvalidOptions:   ErrInvalidOptions,
+	ErrCodePermissionDenied: ErrPermissionDenied,
+	ErrCodeTimeout:          ErrTimeout,
+	ErrCodeUnavailable:      ErrUnavailable,
+}
+
+// DriverError is an Err from a driver response. errors.Is matches it against
+// the sentinel for its code, e.g. ErrNotFound for ErrCodeNotFound, and
+// errors.As finds the SafeError when the driver sent one.
+type DriverError struct {
+	Code    ErrorCode
+	Message string
+	Safe    *SafeError
+}
+
+func NewDriverError(code ErrorCode, message string) *DriverError {
+	driverErr := &DriverError{Code: code, Message: message}
+	if safeError, ok := ParseSafeError(message); ok {
+		driverErr.Safe = &safeError
+	}
+	return driverErr
+}
+
+// Error is the message as the driver sent it, so a SafeError stays encoded
+// when it is passed on.
+func (e *DriverError) Error() string {
+	return e.Message
+}
+
+func (e *DriverError) Unwrap() error {
+	if e.Safe == nil {
+		return nil
+	}
+	return *e.Safe
+}
+
+func (e *DriverError) Is(target error) bool {
+	sentinel, ok := errorsByCode[e.Code]
+	return ok && target == sentinel
+}
+
+// ErrorCodeOf returns the code of the first DriverError in err's chain, or ""
+// if there is none.
+func ErrorCodeOf(err error) ErrorCode {
+	var driverErr *DriverError
+	if errors.As(err, &driverErr) {
+		return driverErr.Code
+	}
+	return ""
+}
+
+// ResponseError turns a response's Err and ErrCode into a *DriverError, or
+// nil if there is no error. A missing code is filled in by ClassifyError.
+func ResponseError(message string, code ErrorCode) error {
+	if message == "" {
+		return nil
+	}
+	if code == "" {
+		code = ClassifyError(message)
+	}
+	return NewDriverError(code, message)
+}
+
+var classifications = []struct {
+	code      ErrorCode
+	fragments []string
+}{
+	{ErrCodeTimeout, []string{"timed out", "timeout", "deadline exceeded"}},
+	{ErrCodeUnavailable, []string{"connection refused", "no such host", "unavailable", "circuit breaker is open"}},
+	{ErrCodePermissionDenied, []string{"permission denied", "not permitted", "access denied", "unauthorized", "forbidden"}},
+	{ErrCodeNotFound, []string{"does not exist", "not found", "no such"}},
+	{ErrCodeAlreadyExists, []string{"already exists"}},
+	{ErrCodeInUse, []string{"in use", "busy"}},
+	{ErrCodeInvalidOptions, []string{"invalid", "missing", "not allowed", "unknown option", "required"}},
+}
+
+// ClassifyError guesses a code from the wording of an error message, for
+// drivers that only send Err. It returns "" when nothing matches.
+func ClassifyError(message string) ErrorCode {
+	message = strings.ToLower(message)
+	for _, c := range classifications {
+		for _, fragment := range c.fragments {
+			if strings.Contains(message, fragment) {
+				return c.code
+			}
+		}
+	}
+	return ""
+}
+
+// DriverError returns the response's Err as a *DriverError, or nil.
+func (r ActivateResponse) DriverError() error {
+	return ResponseError(r.Err, r.ErrCode)
+}
+
+func (r MountResponse) DriverError() error {
+	return ResponseError(r.Err, r.ErrCode)
+}
+
+func (r ListResponse) DriverError() error {
+	return ResponseError(r.Err, r.ErrCode)
+}
+
+func (r PathResponse) DriverError() error {
+	return ResponseError(r.Err, r.ErrCode)
+}
+
+func (r ErrorResponse) DriverError() error {
+	return ResponseError(r.Err, r.ErrCode)
+}
+
+func (r GetResponse) DriverError() error {
+	return ResponseError(r.Err, r.ErrCode)
+}
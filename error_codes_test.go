@@ -0,0 +1,69 @@
+package dockerdriver_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"code.cloudfoundry.org/dockerdriver"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ErrorCodes", func() {
+	Context("when a response is serialized", func() {
+		It("should send the code next to the error", func() {
+			body, err := json.Marshal(dockerdriver.MountResponse{Err: "volume does not exist", ErrCode: dockerdriver.ErrCodeNotFound})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(body).To(MatchJSON(`{"Err":"volume does not exist","ErrCode":"not-found","Mountpoint":""}`))
+		})
+
+		It("should leave the code out when there is none", func() {
+			body, err := json.Marshal(dockerdriver.ErrorResponse{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(body).To(MatchJSON(`{"Err":""}`))
+		})
+	})
+
+	Describe("DriverError", func() {
+		It("should match the sentinel for its code", func() {
+			err := fmt.Errorf("mounting: %w", dockerdriver.NewDriverError(dockerdriver.ErrCodeInUse, "volume is busy"))
+
+			Expect(errors.Is(err, dockerdriver.ErrInUse)).To(BeTrue())
+			Expect(errors.Is(err, dockerdriver.ErrNotFound)).To(BeFalse())
+			Expect(dockerdriver.ErrorCodeOf(err)).To(Equal(dockerdriver.ErrCodeInUse))
+			Expect(err).To(MatchError("mounting: volume is busy"))
+		})
+	})
+
+	Describe("Response DriverError", func() {
+		It("should be nil without an error", func() {
+			Expect(dockerdriver.GetResponse{}.DriverError()).To(BeNil())
+		})
+
+		It("should use the code the driver sent", func() {
+			err := dockerdriver.ErrorResponse{Err: "nope", ErrCode: dockerdriver.ErrCodePermissionDenied}.DriverError()
+			Expect(err).To(MatchError(dockerdriver.ErrPermissionDenied))
+			Expect(err).To(MatchError("nope"))
+		})
+
+		It("should classify the error when the driver sent no code", func() {
+			err := dockerdriver.GetResponse{Err: "Volume fake-volume does not exist"}.DriverError()
+			Expect(err).To(MatchError(dockerdriver.ErrNotFound))
+		})
+	})
+
+	DescribeTable("ClassifyError",
+		func(message string, code dockerdriver.ErrorCode) {
+			Expect(dockerdriver.ClassifyError(message)).To(Equal(code))
+		},
+		Entry("not found", "Volume fake-volume does not exist", dockerdriver.ErrCodeNotFound),
+		Entry("already exists", "volume already exists", dockerdriver.ErrCodeAlreadyExists),
+		Entry("in use", "umount: /var/vcap/data/volumes: target is busy", dockerdriver.ErrCodeInUse),
+		Entry("invalid options", "Invalid option: uid", dockerdriver.ErrCodeInvalidOptions),
+		Entry("permission denied", "mount.nfs: access denied by server", dockerdriver.ErrCodePermissionDenied),
+		Entry("timeout", "context deadline exceeded", dockerdriver.ErrCodeTimeout),
+		Entry("unavailable", "dial tcp 127.0.0.1:8080: connect: connection refused", dockerdriver.ErrCodeUnavailable),
+		Entry("unknown", "something went wrong", dockerdriver.ErrorCode("")),
+	)
+})
@@ -44,6 +44,9 @@ var _ = Describe("Compatibility", func() {
 	})
 
 	It("verifies driver name", func() {
+		if config.DriverName == "localdriver" {
+			Skip("localdriver has no nfs or smb options to check")
+		}
 		Expect([]string{"smbdriver", "nfsv3driver"}).To(ContainElement(config.DriverName))
 	})
 
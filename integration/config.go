@@ -7,6 +7,17 @@ import (
 	"code.cloudfoundry.org/dockerdriver"
 )
 
+// Config is read from the file named by $CONFIG. The fixtures run the suites
+// against this repo's localdriver, once it is installed with
+// go install ./cmd/localdriver:
+//
+//   - fixtures/localdriver.json needs no root. Its volumes are symlinks rather
+//     than mounts, so lazy_unmount checks the mountpoint is gone rather than
+//     looking for it in the mount table.
+//   - fixtures/localdriver-bindmounts.json bind mounts volumes, which needs
+//     root, and checks the mount table too.
+//
+// compatibility only covers nfsv3driver and smbdriver, and skips localdriver.
 type Config struct {
 	CreateConfig  dockerdriver.CreateRequest `json:"create_config"`
 	Driver        string                     `json:"driver"`
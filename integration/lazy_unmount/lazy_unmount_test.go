@@ -27,6 +27,7 @@ var _ = Describe("LazyUnmount", func() {
 		errResponse  dockerdriver.ErrorResponse
 
 		mountResponse dockerdriver.MountResponse
+		symlinked     bool
 	)
 
 	BeforeEach(func() {
@@ -64,8 +65,13 @@ var _ = Describe("LazyUnmount", func() {
 				Expect(mountResponse.Err).To(Equal(""))
 				Expect(mountResponse.Mountpoint).NotTo(Equal(""))
 
-				cmd := exec.Command("bash", "-c", "cat /proc/mounts | grep -E '"+mountResponse.Mountpoint+"'")
-				Expect(cmdRunner(cmd)).To(Equal(0))
+				// localdriver without root links its volumes rather than mounting
+				// them, so only the mountpoint going away can be checked
+				symlinked = config.DriverName == "localdriver" && isSymlink(mountResponse.Mountpoint)
+				if !symlinked {
+					cmd := exec.Command("bash", "-c", "cat /proc/mounts | grep -E '"+mountResponse.Mountpoint+"'")
+					Expect(cmdRunner(cmd)).To(Equal(0))
+				}
 			})
 
 			Context("when the nfs server has a file handle kept open during umount", func() {
@@ -89,6 +95,12 @@ var _ = Describe("LazyUnmount", func() {
 					})
 					Expect(errResponse.Err).To(Equal(""))
 
+					if symlinked {
+						_, err := os.Lstat(mountResponse.Mountpoint)
+						Expect(os.IsNotExist(err)).To(BeTrue())
+						return
+					}
+
 					Eventually(func() int {
 						cmd := exec.Command("bash", "-c", "cat /proc/mounts | grep -E '"+mountResponse.Mountpoint+"'")
 						return cmdRunner(cmd)
@@ -100,6 +112,11 @@ var _ = Describe("LazyUnmount", func() {
 	})
 })
 
+func isSymlink(path string) bool {
+	info, err := os.Lstat(path)
+	return err == nil && info.Mode()&os.ModeSymlink != 0
+}
+
 func cmdRunner(cmd *exec.Cmd) int {
 	session, err := Start(cmd, GinkgoWriter, GinkgoWriter)
 	Expect(err).NotTo(HaveOccurred())
@@ -0,0 +1,238 @@
+package dockerdriver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"code.cloudfoundry.org/lager/v3"
+)
+
+// PluginConfigFile is the name of the manifest docker reads from the root of
+// a managed (v2) plugin.
+const PluginConfigFile = "config.json"
+
+const PluginProtocolSchemeHTTPV1 = "moby.plugins.http/v1"
+
+var VolumeDriverInterfaceType = PluginInterfaceType{Prefix: "docker", Capability: "volumedriver", Version: "1.0"}
+
+var ErrInvalidPluginConfig = errors.New("plugin config is invalid")
+
+// PluginConfig mirrors the config.json manifest of a docker managed plugin.
+// Field names match docker's, so manifests round trip through docker tooling.
+type PluginConfig struct {
+	Description     string          `json:"Description"`
+	Documentation   string          `json:"Documentation"`
+	Entrypoint      []string        `json:"Entrypoint"`
+	WorkDir         string          `json:"WorkDir"`
+	User            PluginUser      `json:"User,omitempty"`
+	Network         PluginNetwork   `json:"Network"`
+	Interface       PluginInterface `json:"Interface"`
+	Linux           PluginLinux     `json:"Linux"`
+	Mounts          []PluginMount   `json:"Mounts"`
+	Env             []PluginEnv     `json:"Env"`
+	Args            PluginArgs      `json:"Args"`
+	PropagatedMount string          `json:"PropagatedMount"`
+	IpcHost         bool            `json:"IpcHost"`
+	PidHost         bool            `json:"PidHost"`
+	DockerVersion   string          `json:"DockerVersion,omitempty"`
+	Rootfs          *PluginRootfs   `json:"rootfs,omitempty"`
+}
+
+type PluginInterface struct {
+	Types          []PluginInterfaceType `json:"Types"`
+	Socket         string                `json:"Socket"`
+	ProtocolScheme string                `json:"ProtocolScheme,omitempty"`
+}
+
+// PluginInterfaceType is serialized the way docker does, as
+// "<prefix>.<capability>/<version>", e.g. "docker.volumedriver/1.0".
+type PluginInterfaceType struct {
+	Prefix     string
+	Capability string
+	Version    string
+}
+
+func (t PluginInterfaceType) String() string {
+	return fmt.Sprintf("%s.%s/%s", t.Prefix, t.Capability, t.Version)
+}
+
+func (t PluginInterfaceType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+func (t *PluginInterfaceType) UnmarshalText(text []byte) error {
+	value := string(text)
+	typeAndVersion := strings.SplitN(value, "/", 2)
+	if len(typeAndVersion) != 2 {
+		return fmt.Errorf("%w: interface type %q has no version", ErrInvalidPluginConfig, value)
+	}
+	prefixAndCapability := strings.SplitN(typeAndVersion[0], ".", 2)
+	if len(prefixAndCapability) != 2 {
+		return fmt.Errorf("%w: interface type %q has no prefix", ErrInvalidPluginConfig, value)
+	}
+	*t = PluginInterfaceType{Prefix: prefixAndCapability[0], Capability: prefixAndCapability[1], Version: typeAndVersion[1]}
+	return nil
+}
+
+type PluginUser struct {
+	UID uint32 `json:"UID,omitempty"`
+	GID uint32 `json:"GID,omitempty"`
+}
+
+type PluginNetwork struct {
+	Type string `json:"Type"`
+}
+
+type PluginLinux struct {
+	Capabilities    []string       `json:"Capabilities"`
+	AllowAllDevices bool           `json:"AllowAllDevices"`
+	Devices         []PluginDevice `json:"Devices"`
+}
+
+type PluginDevice struct {
+	Name        string   `json:"Name"`
+	Description string   `json:"Description"`
+	Settable    []string `json:"Settable"`
+	Path        string   `json:"Path,omitempty"`
+}
+
+type PluginMount struct {
+	Name        string   `json:"Name"`
+	Description string   `json:"Description"`
+	Settable    []string `json:"Settable"`
+	Source      string   `json:"Source,omitempty"`
+	Destination string   `json:"Destination"`
+	Type        string   `json:"Type"`
+	Options     []string `json:"Options"`
+}
+
+type PluginEnv struct {
+	Name        string   `json:"Name"`
+	Description string   `json:"Description"`
+	Settable    []string `json:"Settable"`
+	Value       string   `json:"Value,omitempty"`
+}
+
+type PluginArgs struct {
+	Name        string   `json:"Name"`
+	Description string   `json:"Description"`
+	Settable    []string `json:"Settable"`
+	Value       []string `json:"Value"`
+}
+
+type PluginRootfs struct {
+	Type    string   `json:"type,omitempty"`
+	DiffIds []string `json:"diff_ids,omitempty"`
+}
+
+// NewVolumeDriverPluginConfig returns a manifest for a volume driver that
+// listens on socket, runs on the host network (so it can reach file servers)
+// with CAP_SYS_ADMIN to mount, and propagates mounts made under
+// propagatedMount back to the host.
+func NewVolumeDriverPluginConfig(description string, entrypoint []string, socket string, propagatedMount string) PluginConfig {
+	return PluginConfig{
+		Description: description,
+		Entrypoint:  entrypoint,
+		Network:     PluginNetwork{Type: "host"},
+		Interface: PluginInterface{
+			Types:  []PluginInterfaceType{VolumeDriverInterfaceType},
+			Socket: socket,
+		},
+		Linux: PluginLinux{
+			Capabilities: []string{"CAP_SYS_ADMIN"},
+		},
+		PropagatedMount: propagatedMount,
+	}
+}
+
+func (c PluginConfig) Validate() error {
+	if len(c.Entrypoint) == 0 {
+		return fmt.Errorf("%w: no entrypoint", ErrInvalidPluginConfig)
+	}
+	if len(c.Interface.Types) == 0 {
+		return fmt.Errorf("%w: no interface types", ErrInvalidPluginConfig)
+	}
+	if c.Interface.Socket == "" {
+		return fmt.Errorf("%w: no interface socket", ErrInvalidPluginConfig)
+	}
+	if strings.Contains(c.Interface.Socket, "/") {
+		return fmt.Errorf("%w: interface socket %q must be a file name", ErrInvalidPluginConfig, c.Interface.Socket)
+	}
+	if c.Interface.ProtocolScheme != "" && c.Interface.ProtocolScheme != PluginProtocolSchemeHTTPV1 {
+		return fmt.Errorf("%w: unsupported protocol scheme %q", ErrInvalidPluginConfig, c.Interface.ProtocolScheme)
+	}
+	if c.PropagatedMount != "" && !filepath.IsAbs(c.PropagatedMount) {
+		return fmt.Errorf("%w: propagated mount %q must be absolute", ErrInvalidPluginConfig, c.PropagatedMount)
+	}
+	return nil
+}
+
+func (c PluginConfig) Implements(interfaceType PluginInterfaceType) bool {
+	for _, t := range c.Interface.Types {
+		if t.Prefix == interfaceType.Prefix && t.Capability == interfaceType.Capability {
+			return true
+		}
+	}
+	return false
+}
+
+// DriverSpec returns the spec for talking to the plugin, whose socket docker
+// creates in pluginRoot (the plugin's runtime directory, usually
+// /run/docker/plugins/<plugin id>).
+func (c PluginConfig) DriverSpec(name string, pluginRoot string) (*DriverSpec, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	if !c.Implements(VolumeDriverInterfaceType) {
+		return nil, fmt.Errorf("%w: plugin %s does not implement %s", ErrInvalidPluginConfig, name, VolumeDriverInterfaceType)
+	}
+	return &DriverSpec{
+		Name:    name,
+		Address: filepath.Join(pluginRoot, c.Interface.Socket),
+	}, nil
+}
+
+func GeneratePluginConfig(config PluginConfig) ([]byte, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(config, "", "  ")
+}
+
+func ReadPluginConfig(logger lager.Logger, pluginDirectory string) (*PluginConfig, error) {
+	logger = logger.Session("read-plugin-config", lager.Data{"plugin-directory": pluginDirectory})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	configFile, err := os.Open(filepath.Join(pluginDirectory, PluginConfigFile))
+	if err != nil {
+		logger.Error("error-opening-config", err)
+		return nil, err
+	}
+	defer configFile.Close()
+
+	var config PluginConfig
+	if err := json.NewDecoder(configFile).Decode(&config); err != nil {
+		logger.Error("parsing-config-file-error", err)
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPluginConfig, err)
+	}
+	if err := config.Validate(); err != nil {
+		logger.Error("invalid-plugin-config", err)
+		return nil, err
+	}
+	return &config, nil
+}
+
+// WritePluginConfig writes config.json into pluginDirectory atomically.
+func WritePluginConfig(logger lager.Logger, pluginDirectory string, config PluginConfig) error {
+	contents, err := GeneratePluginConfig(config)
+	if err != nil {
+		logger.Error("error-generating-plugin-config", err)
+		return err
+	}
+	return WriteDriverSpec(logger, pluginDirectory, strings.TrimSuffix(PluginConfigFile, ".json"), "json", contents)
+}
@@ -0,0 +1,95 @@
+package dockerdriver_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PluginConfig", func() {
+	var (
+		testLogger *lagertest.TestLogger
+		pluginDir  string
+		config     dockerdriver.PluginConfig
+	)
+
+	BeforeEach(func() {
+		testLogger = lagertest.NewTestLogger("plugin-config-test")
+		pluginDir = GinkgoT().TempDir()
+		config = dockerdriver.NewVolumeDriverPluginConfig("nfs volumes", []string{"/nfsv3driver"}, "nfsv3driver.sock", "/mnt/volumes")
+	})
+
+	It("should serialize interface types the way docker does", func() {
+		contents, err := dockerdriver.GeneratePluginConfig(config)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(contents)).To(ContainSubstring(`"docker.volumedriver/1.0"`))
+	})
+
+	It("should round trip through config.json", func() {
+		Expect(dockerdriver.WritePluginConfig(testLogger, pluginDir, config)).To(Succeed())
+
+		readConfig, err := dockerdriver.ReadPluginConfig(testLogger, pluginDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*readConfig).To(Equal(config))
+	})
+
+	It("should read a manifest written by hand in docker's format", func() {
+		manifest := `{
+			"description": "sample volume plugin",
+			"entrypoint": ["/usr/bin/sample-volume-plugin", "/data"],
+			"env": [{"name": "DEBUG", "settable": ["value"], "value": "0"}],
+			"interface": {"socket": "plugin.sock", "types": ["docker.volumedriver/1.0"]},
+			"linux": {"capabilities": ["CAP_SYS_ADMIN"], "devices": [{"path": "/dev/fuse"}]},
+			"mounts": [{"source": "/data", "destination": "/data", "type": "bind", "options": ["shared", "rbind"]}],
+			"network": {"type": "host"},
+			"propagatedMount": "/data"
+		}`
+		Expect(os.WriteFile(filepath.Join(pluginDir, dockerdriver.PluginConfigFile), []byte(manifest), 0644)).To(Succeed())
+
+		readConfig, err := dockerdriver.ReadPluginConfig(testLogger, pluginDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(readConfig.Interface.Types).To(Equal([]dockerdriver.PluginInterfaceType{dockerdriver.VolumeDriverInterfaceType}))
+		Expect(readConfig.Env[0].Value).To(Equal("0"))
+		Expect(readConfig.Mounts[0].Options).To(Equal([]string{"shared", "rbind"}))
+		Expect(readConfig.Linux.Devices[0].Path).To(Equal("/dev/fuse"))
+		Expect(readConfig.PropagatedMount).To(Equal("/data"))
+	})
+
+	It("should reject a malformed interface type", func() {
+		var t dockerdriver.PluginInterfaceType
+		Expect(json.Unmarshal([]byte(`"volumedriver"`), &t)).To(MatchError(dockerdriver.ErrInvalidPluginConfig))
+	})
+
+	DescribeTable("invalid manifests",
+		func(mutate func(*dockerdriver.PluginConfig)) {
+			mutate(&config)
+			_, err := dockerdriver.GeneratePluginConfig(config)
+			Expect(err).To(MatchError(dockerdriver.ErrInvalidPluginConfig))
+		},
+		Entry("no entrypoint", func(c *dockerdriver.PluginConfig) { c.Entrypoint = nil }),
+		Entry("no socket", func(c *dockerdriver.PluginConfig) { c.Interface.Socket = "" }),
+		Entry("socket with a path", func(c *dockerdriver.PluginConfig) { c.Interface.Socket = "/run/plugin.sock" }),
+		Entry("no interface types", func(c *dockerdriver.PluginConfig) { c.Interface.Types = nil }),
+		Entry("relative propagated mount", func(c *dockerdriver.PluginConfig) { c.PropagatedMount = "volumes" }),
+	)
+
+	Describe("DriverSpec", func() {
+		It("should address the socket in the plugin root", func() {
+			spec, err := config.DriverSpec("nfsv3driver", "/run/docker/plugins/abc123")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*spec).To(Equal(dockerdriver.DriverSpec{Name: "nfsv3driver", Address: "/run/docker/plugins/abc123/nfsv3driver.sock"}))
+		})
+
+		It("should refuse a plugin that is not a volume driver", func() {
+			config.Interface.Types = []dockerdriver.PluginInterfaceType{{Prefix: "docker", Capability: "networkdriver", Version: "1.0"}}
+
+			_, err := config.DriverSpec("some-plugin", "/run/docker/plugins/abc123")
+			Expect(err).To(MatchError(dockerdriver.ErrInvalidPluginConfig))
+		})
+	})
+})
@@ -66,6 +66,7 @@ type Provisioner interface {
 
 type ActivateResponse struct {
 	Err        string
+	ErrCode    ErrorCode `json:"ErrCode,omitempty"`
 	Implements []string
 }
 
@@ -76,16 +77,19 @@ type CreateRequest struct {
 
 type MountRequest struct {
 	Name string
+	ID   string
 }
 
 type MountResponse struct {
 	Err        string
+	ErrCode    ErrorCode `json:"ErrCode,omitempty"`
 	Mountpoint string
 }
 
 type ListResponse struct {
 	Volumes []VolumeInfo
 	Err     string
+	ErrCode ErrorCode `json:"ErrCode,omitempty"`
 }
 
 type PathRequest struct {
@@ -94,11 +98,13 @@ type PathRequest struct {
 
 type PathResponse struct {
 	Err        string
+	ErrCode    ErrorCode `json:"ErrCode,omitempty"`
 	Mountpoint string
 }
 
 type UnmountRequest struct {
 	Name string
+	ID   string
 }
 
 type RemoveRequest struct {
@@ -106,7 +112,8 @@ type RemoveRequest struct {
 }
 
 type ErrorResponse struct {
-	Err string
+	Err     string
+	ErrCode ErrorCode `json:"ErrCode,omitempty"`
 }
 
 type GetRequest struct {
@@ -114,8 +121,9 @@ type GetRequest struct {
 }
 
 type GetResponse struct {
-	Volume VolumeInfo
-	Err    string
+	Volume  VolumeInfo
+	Err     string
+	ErrCode ErrorCode `json:"ErrCode,omitempty"`
 }
 
 type CapabilitiesResponse struct {
@@ -126,6 +134,7 @@ type VolumeInfo struct {
 	Name       string
 	Mountpoint string
 	MountCount int
+	Status     map[string]interface{} `json:"Status,omitempty"`
 }
 
 type CapabilityInfo struct {
@@ -0,0 +1,26 @@
+package dockerdriver
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// SafeErrorMessage encodes err as JSON for a response's Err, which is where
+// callers such as volman look for a SafeError.
+func SafeErrorMessage(err SafeError) string {
+	message, _ := json.Marshal(err)
+	return string(message)
+}
+
+// ParseSafeError recognizes an Err that carries a SafeError.
+func ParseSafeError(message string) (SafeError, bool) {
+	if !strings.HasPrefix(strings.TrimSpace(message), "{") {
+		return SafeError{}, false
+	}
+
+	var safeError SafeError
+	if err := json.Unmarshal([]byte(message), &safeError); err != nil || safeError.SafeDescription == "" {
+		return SafeError{}, false
+	}
+	return safeError, true
+}
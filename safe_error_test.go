@@ -0,0 +1,28 @@
+package dockerdriver_test
+
+import (
+	"code.cloudfoundry.org/dockerdriver"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SafeError", func() {
+	It("should round trip through a response's Err", func() {
+		message := dockerdriver.SafeErrorMessage(dockerdriver.SafeError{SafeDescription: "share is not reachable"})
+		Expect(message).To(MatchJSON(`{"SafeDescription":"share is not reachable"}`))
+
+		safeError, ok := dockerdriver.ParseSafeError(message)
+		Expect(ok).To(BeTrue())
+		Expect(safeError.SafeDescription).To(Equal("share is not reachable"))
+	})
+
+	It("should not find a SafeError in a plain message", func() {
+		_, ok := dockerdriver.ParseSafeError("mount failed")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should not find a SafeError in other json", func() {
+		_, ok := dockerdriver.ParseSafeError(`{"Reason":"mount failed"}`)
+		Expect(ok).To(BeFalse())
+	})
+})
@@ -0,0 +1,88 @@
+package dockerdriver
+
+import "fmt"
+
+// Well known keys for VolumeInfo.Status. Drivers should use these (and the
+// helpers below) rather than ad hoc keys so that operators see the same
+// fields regardless of which driver reports them.
+const (
+	StatusHealth       = "health"
+	StatusSource       = "source"
+	StatusMountOptions = "mount_options"
+	StatusLastError    = "last_error"
+)
+
+type VolumeHealth string
+
+const (
+	VolumeHealthUnknown VolumeHealth = "unknown"
+	VolumeHealthy       VolumeHealth = "healthy"
+	VolumeUnhealthy     VolumeHealth = "unhealthy"
+)
+
+func (v *VolumeInfo) SetStatus(key string, value interface{}) {
+	if v.Status == nil {
+		v.Status = map[string]interface{}{}
+	}
+	v.Status[key] = value
+}
+
+func (v *VolumeInfo) SetHealth(health VolumeHealth) {
+	v.SetStatus(StatusHealth, string(health))
+}
+
+// Health returns VolumeHealthUnknown when the driver did not report a health.
+func (v VolumeInfo) Health() VolumeHealth {
+	if health, ok := v.statusString(StatusHealth); ok && health != "" {
+		return VolumeHealth(health)
+	}
+	return VolumeHealthUnknown
+}
+
+func (v *VolumeInfo) SetSource(source string) {
+	v.SetStatus(StatusSource, source)
+}
+
+func (v VolumeInfo) Source() string {
+	source, _ := v.statusString(StatusSource)
+	return source
+}
+
+func (v *VolumeInfo) SetMountOptions(opts map[string]string) {
+	v.SetStatus(StatusMountOptions, opts)
+}
+
+// MountOptions accepts both the map set by SetMountOptions and the
+// map[string]interface{} produced by decoding a Get or List response.
+func (v VolumeInfo) MountOptions() map[string]string {
+	switch opts := v.Status[StatusMountOptions].(type) {
+	case map[string]string:
+		return opts
+	case map[string]interface{}:
+		result := make(map[string]string, len(opts))
+		for key, value := range opts {
+			result[key] = fmt.Sprintf("%v", value)
+		}
+		return result
+	}
+	return nil
+}
+
+// SetLastError records err, or clears the last error when err is nil.
+func (v *VolumeInfo) SetLastError(err error) {
+	if err == nil {
+		delete(v.Status, StatusLastError)
+		return
+	}
+	v.SetStatus(StatusLastError, err.Error())
+}
+
+func (v VolumeInfo) LastError() string {
+	lastError, _ := v.statusString(StatusLastError)
+	return lastError
+}
+
+func (v VolumeInfo) statusString(key string) (string, bool) {
+	value, ok := v.Status[key].(string)
+	return value, ok
+}
@@ -0,0 +1,63 @@
+package dockerdriver_test
+
+import (
+	"encoding/json"
+	"errors"
+
+	"code.cloudfoundry.org/dockerdriver"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("VolumeInfo Status", func() {
+	var volume dockerdriver.VolumeInfo
+
+	BeforeEach(func() {
+		volume = dockerdriver.VolumeInfo{Name: "some-volume"}
+	})
+
+	It("should omit the status when none is set", func() {
+		bytes, err := json.Marshal(volume)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(bytes)).NotTo(ContainSubstring("Status"))
+	})
+
+	It("should report unknown health when none is set", func() {
+		Expect(volume.Health()).To(Equal(dockerdriver.VolumeHealthUnknown))
+	})
+
+	Context("when the well known fields are set", func() {
+		BeforeEach(func() {
+			volume.SetHealth(dockerdriver.VolumeUnhealthy)
+			volume.SetSource("nfs://some-server/some/share")
+			volume.SetMountOptions(map[string]string{"vers": "4.1", "ro": "true"})
+			volume.SetLastError(errors.New("stale file handle"))
+		})
+
+		It("should read them back", func() {
+			Expect(volume.Health()).To(Equal(dockerdriver.VolumeUnhealthy))
+			Expect(volume.Source()).To(Equal("nfs://some-server/some/share"))
+			Expect(volume.MountOptions()).To(Equal(map[string]string{"vers": "4.1", "ro": "true"}))
+			Expect(volume.LastError()).To(Equal("stale file handle"))
+		})
+
+		It("should read them back after a json round trip", func() {
+			bytes, err := json.Marshal(volume)
+			Expect(err).NotTo(HaveOccurred())
+
+			var decoded dockerdriver.VolumeInfo
+			Expect(json.Unmarshal(bytes, &decoded)).To(Succeed())
+
+			Expect(decoded.Health()).To(Equal(dockerdriver.VolumeUnhealthy))
+			Expect(decoded.Source()).To(Equal("nfs://some-server/some/share"))
+			Expect(decoded.MountOptions()).To(Equal(map[string]string{"vers": "4.1", "ro": "true"}))
+			Expect(decoded.LastError()).To(Equal("stale file handle"))
+		})
+
+		It("should clear the last error", func() {
+			volume.SetLastError(nil)
+			Expect(volume.LastError()).To(BeEmpty())
+			Expect(volume.Status).NotTo(HaveKey(dockerdriver.StatusLastError))
+		})
+	})
+})